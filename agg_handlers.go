@@ -2,14 +2,28 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/timpinoy/bd-aggregator/internal/database"
 	"log"
+	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
 
+const (
+	defaultMaxFeedFailures = 10
+	minFailureBackoff      = time.Minute
+	maxFailureBackoff      = 24 * time.Hour
+	feedFetchRetention     = 30 * 24 * time.Hour
+	feedFetchPruneInterval = time.Hour
+	articleBatchSize       = 10
+	maxArticleAttempts     = 5
+)
+
 func handlerAggregate(s *state, cmd command) error {
 	if len(cmd.Args) != 1 {
 		return fmt.Errorf("usage: %s <time_between_reqs>", cmd.Name)
@@ -20,17 +34,59 @@ func handlerAggregate(s *state, cmd command) error {
 		return fmt.Errorf("parsing of duration failed: %w", err)
 	}
 
+	if s.cfg.WebSubCallback != "" {
+		go serveWebSub(s)
+	}
+	go collectArticles(s, timeBetweenRequests)
+
 	ticker := time.NewTicker(timeBetweenRequests)
 	log.Printf("Collecting feeds every %v\n", timeBetweenRequests)
+	var lastPruned time.Time
 	for ; ; <-ticker.C {
+		if time.Since(lastPruned) >= feedFetchPruneInterval {
+			pruneFeedFetches(s)
+			lastPruned = time.Now()
+		}
 		scrapeNextFeed(s)
+		if s.cfg.WebSubCallback != "" {
+			renewWebSubSubscriptions(s)
+		}
 	}
 }
 
 func scrapeNextFeed(s *state) {
-	feedToFetch, err := s.db.GetNextFeedToFetch(context.Background())
+	feedToFetch, err := s.db.GetNextFeedToFetch(context.Background(), time.Now().UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("No feeds due for fetching")
+		return
+	}
 	if err != nil {
 		log.Printf("retrieval of next feed to fetch failed: %v", err)
+		return
+	}
+
+	creds, err := loadFeedCredentials(s, feedToFetch.ID)
+	if err != nil {
+		recordFeedFailure(s, feedToFetch, fmt.Errorf("retrieval of feed credentials failed: %v", err))
+		return
+	}
+
+	parse, err := feedParser(feedToFetch)
+	if err != nil {
+		recordFeedFailure(s, feedToFetch, err)
+		return
+	}
+
+	host := hostOf(feedToFetch.Url)
+	err = s.hosts.wait(context.Background(), host)
+	if err != nil {
+		var deferredErr *hostDeferredError
+		if errors.As(err, &deferredErr) {
+			deferFeed(s, feedToFetch, deferredErr.Until)
+			return
+		}
+		log.Printf("waiting for %s failed: %v", host, err)
+		return
 	}
 
 	err = s.db.MarkFeedFetched(context.Background(), feedToFetch.ID)
@@ -38,36 +94,418 @@ func scrapeNextFeed(s *state) {
 		log.Printf("marking of fetched feed failed: %v", err)
 	}
 
-	feed, err := fetchFeed(context.Background(), feedToFetch.Url)
+	fetchStarted := time.Now()
+	result, err := s.fetcher.FetchFeed(context.Background(), fetchRequest{
+		URL:          feedToFetch.Url,
+		ETag:         feedToFetch.Etag.String,
+		LastModified: feedToFetch.LastModified.String,
+		MaxBytes:     s.cfg.MaxFeedBytes,
+		Header:       creds.header(),
+		Parse:        parse,
+	})
+	fetchLog := newFeedFetch(feedToFetch.ID, time.Since(fetchStarted), result, err)
+	defer func() {
+		recordFeedFetch(s, fetchLog)
+	}()
 	if err != nil {
-		log.Printf("fetching of feed failed: %v", err)
+		var tooLargeErr *feedTooLargeError
+		if errors.As(err, &tooLargeErr) {
+			log.Printf("Feed %s is larger than the %d byte limit, skipping", feedToFetch.Name, tooLargeErr.Limit)
+			recordFeedFailure(s, feedToFetch, err)
+			return
+		}
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && statusErr.Throttled {
+			// A Retry-After of 0 asks for a retry right away, which the host interval still spaces out.
+			now := time.Now().UTC()
+			until := now.Add(max(statusErr.RetryAfter, s.hosts.interval))
+			s.hosts.deferHost(host, until)
+			deferFeed(s, feedToFetch, notBeforeHints(feedToFetch, now, until))
+			return
+		}
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusGone {
+			log.Printf("Feed %s is gone, it will no longer be fetched", feedToFetch.Name)
+			if err := s.db.MarkFeedGone(context.Background(), feedToFetch.ID); err != nil {
+				log.Printf("marking of gone feed failed: %v", err)
+			}
+			return
+		}
+		recordFeedFailure(s, feedToFetch, fmt.Errorf("fetching of feed failed: %v", err))
+		return
+	}
+	recordFeedSuccess(s, feedToFetch)
+	if result.PermanentURL != "" && result.PermanentURL != feedToFetch.Url {
+		if !creds.isEmpty() && hostOf(result.PermanentURL) != hostOf(feedToFetch.Url) {
+			log.Printf("Feed %s moved permanently to %s, keeping the old url so its credentials are not sent to another host", feedToFetch.Name, result.PermanentURL)
+		} else {
+			feedToFetch, err = moveFeed(s, feedToFetch, result.PermanentURL)
+			if err != nil {
+				log.Printf("updating of moved feed url failed: %v", err)
+			}
+		}
+	}
+	hints := loadUpdateHints(feedToFetch)
+	if !result.NotModified {
+		hints = storeUpdateHints(s, feedToFetch, result.Feed)
+	}
+	scheduleFeed(s, feedToFetch, hints)
+
+	if result.NotModified {
+		log.Printf("Aggregated %s, not modified since last fetch", feedToFetch.Name)
+		return
 	}
 
-	for _, item := range feed.Channel.Item {
-		publishedAt, err := time.Parse(time.RFC1123Z, item.PubDate)
+	err = s.db.SetFeedCacheValidators(context.Background(), database.SetFeedCacheValidatorsParams{
+		ID:           feedToFetch.ID,
+		Etag:         sql.NullString{String: result.ETag, Valid: result.ETag != ""},
+		LastModified: sql.NullString{String: result.LastModified, Valid: result.LastModified != ""},
+	})
+	if err != nil {
+		log.Printf("storing of cache validators failed: %v", err)
+	}
+
+	feed := result.Feed
+	updateFeedMetadata(s, feedToFetch, feed)
+
+	fetchLog.ItemCount = int32(len(feed.Channel.Item))
+	postsSaved := savePosts(s, feedToFetch, feed)
+	log.Printf("Aggregated %s, %v new posts saved", feedToFetch.Name, postsSaved)
+
+	if result.Hub != "" {
+		ensureWebSubSubscription(s, feedToFetch, result.Hub, result.Topic)
+	}
+}
+
+func savePosts(s *state, feed database.Feed, parsed *RSSFeed) int {
+	postsSaved := 0
+	for _, item := range parsed.Channel.Item {
+		publishedAtInferred := false
+		publishedAt, err := parsePubDate(item.PubDate)
 		if err != nil {
-			log.Printf("parsing of '%v' to time failed: %v", item.PubDate, err)
+			log.Printf("parsing of '%v' to time failed, using fetch time: %v", item.PubDate, err)
+			publishedAt = time.Now().UTC()
+			publishedAtInferred = true
 		}
 
 		cpp := database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now().UTC(),
-			UpdatedAt:   time.Now().UTC(),
-			Title:       item.Title,
-			Url:         item.Link,
-			Description: item.Description,
-			PublishedAt: publishedAt,
-			FeedID:      feedToFetch.ID,
+			ID:                  uuid.New(),
+			CreatedAt:           time.Now().UTC(),
+			UpdatedAt:           time.Now().UTC(),
+			Title:               item.Title,
+			Url:                 item.Link,
+			Description:         item.Description,
+			PublishedAt:         publishedAt,
+			PublishedAtInferred: publishedAtInferred,
+			FeedID:              feed.ID,
+			Content:             item.Content,
+			Author:              strings.TrimSpace(item.Author),
+			Guid:                item.identity(),
 		}
 
-		_, err = s.db.CreatePost(context.Background(), cpp)
+		post, err := s.db.CreatePost(context.Background(), cpp)
 		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
-				continue
-			}
 			log.Printf("creation of post failed: %v", err)
 			continue
 		}
+		if post.ID == cpp.ID {
+			postsSaved++
+		}
+
+		for _, category := range item.Categories {
+			category = strings.TrimSpace(category)
+			if category == "" {
+				continue
+			}
+			err = s.db.CreatePostCategory(context.Background(), database.CreatePostCategoryParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now().UTC(),
+				UpdatedAt: time.Now().UTC(),
+				PostID:    post.ID,
+				Name:      category,
+			})
+			if err != nil {
+				log.Printf("creation of post category failed: %v", err)
+			}
+		}
+
+		for _, encl := range itemEnclosures(item) {
+			err = s.db.CreateEnclosure(context.Background(), database.CreateEnclosureParams{
+				ID:              uuid.New(),
+				CreatedAt:       time.Now().UTC(),
+				UpdatedAt:       time.Now().UTC(),
+				PostID:          post.ID,
+				Kind:            encl.Kind,
+				Url:             encl.URL,
+				MimeType:        encl.MimeType,
+				Length:          encl.Length,
+				DurationSeconds: encl.DurationSeconds,
+			})
+			if err != nil {
+				log.Printf("creation of enclosure failed: %v", err)
+			}
+		}
+	}
+	return postsSaved
+}
+
+func newFeedMetadata(feed database.Feed, parsed *RSSFeed) database.UpdateFeedMetadataParams {
+	return database.UpdateFeedMetadataParams{
+		ID:          feed.ID,
+		Title:       strings.TrimSpace(parsed.Channel.Title),
+		Description: strings.TrimSpace(parsed.Channel.Description),
+		SiteUrl:     resolveReference(feed.Url, parsed.Channel.Link),
+		ImageUrl:    resolveReference(feed.Url, parsed.Channel.Image.URL),
+		Language:    strings.TrimSpace(parsed.Channel.Language),
+	}
+}
+
+func updateFeedMetadata(s *state, feed database.Feed, parsed *RSSFeed) {
+	params := newFeedMetadata(feed, parsed)
+	if params.Title == feed.Title && params.Description == feed.Description && params.SiteUrl == feed.SiteUrl &&
+		params.ImageUrl == feed.ImageUrl && params.Language == feed.Language {
+		return
+	}
+	if err := s.db.UpdateFeedMetadata(context.Background(), params); err != nil {
+		log.Printf("updating of feed metadata failed: %v", err)
+	}
+}
+
+func resolveReference(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	resolved, err := baseURL.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return resolved.String()
+}
+
+// collectArticles fetches the full articles of new posts apart from the feed
+// fetches, so a feed with many new posts does not hold up the others.
+func collectArticles(s *state, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for ; ; <-ticker.C {
+		fetchArticles(s)
+	}
+}
+
+func fetchArticles(s *state) {
+	posts, err := s.db.GetPostsWithoutArticle(context.Background(), database.GetPostsWithoutArticleParams{
+		MaxAttempts: maxArticleAttempts,
+		Now:         time.Now().UTC(),
+		PostLimit:   articleBatchSize,
+	})
+	if err != nil {
+		log.Printf("retrieval of posts without article failed: %v", err)
+		return
+	}
+
+	credentials := make(map[uuid.UUID]http.Header)
+	for _, post := range posts {
+		header, ok := credentials[post.FeedID]
+		if !ok {
+			creds, err := loadFeedCredentials(s, post.FeedID)
+			if err != nil {
+				log.Printf("retrieval of feed credentials failed: %v", err)
+				continue
+			}
+			header = creds.header()
+			credentials[post.FeedID] = header
+		}
+		// Credentials only go to the host of the feed they were configured for.
+		if hostOf(post.Url) != hostOf(post.FeedUrl) {
+			header = nil
+		}
+		saveArticle(s, post, header)
+	}
+}
+
+func saveArticle(s *state, post database.GetPostsWithoutArticleRow, header http.Header) {
+	if !isHTTPURL(post.Url) {
+		log.Printf("Article %s is not an http or https url, it will not be fetched", post.Url)
+		recordArticleFailure(s, post, maxArticleAttempts)
+		return
+	}
+
+	host := hostOf(post.Url)
+	if err := s.hosts.wait(context.Background(), host); err != nil {
+		log.Printf("waiting for %s failed, article of %s not fetched: %v", host, post.Url, err)
+		return
+	}
+
+	p, err := s.fetcher.FetchPage(context.Background(), post.Url, header)
+	article := ""
+	if err == nil {
+		article, err = extractArticle(p)
+	}
+	if err != nil {
+		attempts := post.ArticleAttempts + 1
+		log.Printf("extraction of article %s failed, attempt %d of %d: %v", post.Url, attempts, maxArticleAttempts, err)
+		recordArticleFailure(s, post, attempts)
+		return
+	}
+
+	err = s.db.SetPostArticle(context.Background(), database.SetPostArticleParams{
+		ID:      post.ID,
+		Article: sql.NullString{String: article, Valid: true},
+	})
+	if err != nil {
+		log.Printf("storing of article failed: %v", err)
+	}
+}
+
+func recordArticleFailure(s *state, post database.GetPostsWithoutArticleRow, attempts int32) {
+	err := s.db.RecordPostArticleFailure(context.Background(), database.RecordPostArticleFailureParams{
+		ID:                 post.ID,
+		ArticleAttempts:    attempts,
+		ArticleNextFetchAt: sql.NullTime{Time: time.Now().UTC().Add(failureBackoff(attempts)), Valid: true},
+	})
+	if err != nil {
+		log.Printf("recording of article failure failed: %v", err)
+	}
+}
+
+func recordFeedFailure(s *state, feed database.Feed, fetchErr error) {
+	log.Printf("Feed %s failed: %v", feed.Name, fetchErr)
+
+	now := time.Now().UTC()
+	failures := feed.ConsecutiveFailures + 1
+	params := database.RecordFeedFailureParams{
+		ID:                  feed.ID,
+		ConsecutiveFailures: failures,
+		LastError:           sql.NullString{String: fetchErr.Error(), Valid: true},
+		NextFetchAt:         sql.NullTime{Time: notBeforeHints(feed, now, now.Add(failureBackoff(failures))), Valid: true},
+	}
+
+	maxFailures := s.cfg.MaxFeedFailures
+	if maxFailures <= 0 {
+		maxFailures = defaultMaxFeedFailures
+	}
+	if failures >= maxFailures {
+		params.DisabledAt = sql.NullTime{Time: now, Valid: true}
+		log.Printf("Feed %s disabled after %d consecutive failures, use enablefeed to re-enable it", feed.Name, failures)
+	} else {
+		log.Printf("Feed %s will be retried after %v", feed.Name, params.NextFetchAt.Time.Format(time.RFC3339))
+	}
+
+	if err := s.db.RecordFeedFailure(context.Background(), params); err != nil {
+		log.Printf("recording of feed failure failed: %v", err)
+	}
+}
+
+func recordFeedSuccess(s *state, feed database.Feed) {
+	if feed.ConsecutiveFailures == 0 && !feed.LastError.Valid {
+		return
+	}
+	if err := s.db.RecordFeedSuccess(context.Background(), feed.ID); err != nil {
+		log.Printf("recording of feed success failed: %v", err)
+	}
+}
+
+func failureBackoff(failures int32) time.Duration {
+	backoff := minFailureBackoff
+	for i := int32(1); i < failures && backoff < maxFailureBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxFailureBackoff)
+}
+
+func newFeedFetch(feedID uuid.UUID, duration time.Duration, result *fetchResult, fetchErr error) database.CreateFeedFetchParams {
+	params := database.CreateFeedFetchParams{
+		ID:         uuid.New(),
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+		FeedID:     feedID,
+		DurationMs: int32(duration.Milliseconds()),
+		Succeeded:  fetchErr == nil,
+	}
+
+	var statusErr *httpStatusError
+	switch {
+	case result != nil:
+		params.StatusCode = sql.NullInt32{Int32: int32(result.StatusCode), Valid: true}
+	case errors.As(fetchErr, &statusErr):
+		params.StatusCode = sql.NullInt32{Int32: int32(statusErr.StatusCode), Valid: true}
+	}
+	if fetchErr != nil {
+		params.Error = sql.NullString{String: fetchErr.Error(), Valid: true}
+	}
+
+	return params
+}
+
+func recordFeedFetch(s *state, params database.CreateFeedFetchParams) {
+	if err := s.db.CreateFeedFetch(context.Background(), params); err != nil {
+		log.Printf("recording of feed fetch failed: %v", err)
+	}
+}
+
+func pruneFeedFetches(s *state) {
+	if err := s.db.DeleteFeedFetchesBefore(context.Background(), time.Now().UTC().Add(-feedFetchRetention)); err != nil {
+		log.Printf("pruning of feed fetches failed: %v", err)
+	}
+}
+
+func deferFeed(s *state, feed database.Feed, until time.Time) {
+	log.Printf("Fetching of %s deferred until %v", feed.Name, until.Format(time.RFC3339))
+	err := s.db.DeferFeed(context.Background(), database.DeferFeedParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: until.UTC(), Valid: true},
+	})
+	if err != nil {
+		log.Printf("deferring of feed failed: %v", err)
+	}
+}
+
+func moveFeed(s *state, feed database.Feed, newURL string) (database.Feed, error) {
+	existing, err := s.db.GetFeedByURL(context.Background(), newURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = s.db.UpdateFeedURL(context.Background(), database.UpdateFeedURLParams{
+			ID:  feed.ID,
+			Url: newURL,
+		})
+		if err != nil {
+			return feed, err
+		}
+		log.Printf("Feed %s moved permanently from %s to %s", feed.Name, feed.Url, newURL)
+		feed.Url = newURL
+		return feed, nil
 	}
-	log.Printf("Aggregated %s, %v posts saved", feedToFetch.Name, len(feed.Channel.Item))
+	if err != nil {
+		return feed, err
+	}
+
+	// Another feed already has the new url. Posts and followers move over to it;
+	// posts it already has stay with the old feed, which is no longer fetched.
+	err = s.db.MovePosts(context.Background(), database.MovePostsParams{
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+	if err != nil {
+		return feed, err
+	}
+	err = s.db.MoveFeedFollows(context.Background(), database.MoveFeedFollowsParams{
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+	if err != nil {
+		return feed, err
+	}
+	err = s.db.DeleteFeedFollowsForFeed(context.Background(), feed.ID)
+	if err != nil {
+		return feed, err
+	}
+	err = s.db.MarkFeedGone(context.Background(), feed.ID)
+	if err != nil {
+		return feed, err
+	}
+	log.Printf("Feed %s moved permanently to %s, merged into existing feed %s", feed.Name, newURL, existing.Name)
+
+	return existing, nil
 }
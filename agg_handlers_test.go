@@ -0,0 +1,1108 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/timpinoy/bd-aggregator/internal/config"
+	"github.com/timpinoy/bd-aggregator/internal/database"
+)
+
+// fakeQuerier keeps a single feed and the rows scrapeNextFeed writes for it in
+// memory. Queries the scraper does not use panic through the nil Querier.
+type fakeQuerier struct {
+	database.Querier
+
+	feed       database.Feed
+	posts      map[string]database.Post
+	categories map[uuid.UUID][]string
+	enclosures map[uuid.UUID][]database.CreateEnclosureParams
+	articles   map[uuid.UUID]string
+	fetches    []database.CreateFeedFetchParams
+	failures   []database.RecordFeedFailureParams
+	deferrals  []database.DeferFeedParams
+
+	otherFeeds  []database.Feed
+	follows     map[uuid.UUID][]uuid.UUID
+	credentials map[uuid.UUID]string
+
+	subscriptions map[uuid.UUID]database.WebsubSubscription
+}
+
+func newFakeQuerier(feed database.Feed) *fakeQuerier {
+	return &fakeQuerier{
+		feed:       feed,
+		posts:      make(map[string]database.Post),
+		categories: make(map[uuid.UUID][]string),
+		enclosures: make(map[uuid.UUID][]database.CreateEnclosureParams),
+		articles:   make(map[uuid.UUID]string),
+		follows:    make(map[uuid.UUID][]uuid.UUID),
+
+		credentials: make(map[uuid.UUID]string),
+
+		subscriptions: make(map[uuid.UUID]database.WebsubSubscription),
+	}
+}
+
+func (q *fakeQuerier) GetNextFeedToFetch(ctx context.Context, now time.Time) (database.Feed, error) {
+	if q.feed.GoneAt.Valid || q.feed.DisabledAt.Valid {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return q.feed, nil
+}
+
+func (q *fakeQuerier) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	q.feed.LastFetchedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	return nil
+}
+
+func (q *fakeQuerier) GetFeedCredentials(ctx context.Context, feedID uuid.UUID) (database.FeedCredential, error) {
+	secret, ok := q.credentials[feedID]
+	if !ok {
+		return database.FeedCredential{}, sql.ErrNoRows
+	}
+	return database.FeedCredential{FeedID: feedID, Secret: secret}, nil
+}
+
+func (q *fakeQuerier) UpsertFeedCredentials(ctx context.Context, arg database.UpsertFeedCredentialsParams) error {
+	q.credentials[arg.FeedID] = arg.Secret
+	return nil
+}
+
+func (q *fakeQuerier) GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	if id == q.feed.ID {
+		return q.feed, nil
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) GetFeedByURL(ctx context.Context, url string) (database.Feed, error) {
+	if url == q.feed.Url {
+		return q.feed, nil
+	}
+	for _, feed := range q.otherFeeds {
+		if url == feed.Url {
+			return feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) GetFeedsByName(ctx context.Context, name string) ([]database.Feed, error) {
+	var feeds []database.Feed
+	if name == q.feed.Name {
+		feeds = append(feeds, q.feed)
+	}
+	return feeds, nil
+}
+
+func (q *fakeQuerier) UpdateFeedURL(ctx context.Context, arg database.UpdateFeedURLParams) error {
+	q.feed.Url = arg.Url
+	return nil
+}
+
+func (q *fakeQuerier) MarkFeedGone(ctx context.Context, id uuid.UUID) error {
+	if id == q.feed.ID {
+		q.feed.GoneAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) MovePosts(ctx context.Context, arg database.MovePostsParams) error {
+	for key, post := range q.posts {
+		if post.FeedID != arg.FromFeedID {
+			continue
+		}
+		if _, ok := q.posts[arg.ToFeedID.String()+" "+post.Guid]; ok {
+			continue
+		}
+		delete(q.posts, key)
+		post.FeedID = arg.ToFeedID
+		q.posts[arg.ToFeedID.String()+" "+post.Guid] = post
+	}
+	return nil
+}
+
+func (q *fakeQuerier) MoveFeedFollows(ctx context.Context, arg database.MoveFeedFollowsParams) error {
+	for _, userID := range q.follows[arg.FromFeedID] {
+		if !slices.Contains(q.follows[arg.ToFeedID], userID) {
+			q.follows[arg.ToFeedID] = append(q.follows[arg.ToFeedID], userID)
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) DeleteFeedFollowsForFeed(ctx context.Context, feedID uuid.UUID) error {
+	delete(q.follows, feedID)
+	return nil
+}
+
+func (q *fakeQuerier) DeferFeed(ctx context.Context, arg database.DeferFeedParams) error {
+	q.deferrals = append(q.deferrals, arg)
+	q.feed.NextFetchAt = arg.NextFetchAt
+	return nil
+}
+
+func (q *fakeQuerier) ScheduleFeed(ctx context.Context, arg database.ScheduleFeedParams) error {
+	if !q.feed.NextFetchAt.Valid || q.feed.NextFetchAt.Time.Before(arg.NextFetchAt) {
+		q.feed.NextFetchAt = sql.NullTime{Time: arg.NextFetchAt, Valid: true}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) SetFeedUpdateHints(ctx context.Context, arg database.SetFeedUpdateHintsParams) error {
+	q.feed.UpdateHints = arg.UpdateHints
+	return nil
+}
+
+func (q *fakeQuerier) RecordFeedFailure(ctx context.Context, arg database.RecordFeedFailureParams) error {
+	q.failures = append(q.failures, arg)
+	q.feed.ConsecutiveFailures = arg.ConsecutiveFailures
+	q.feed.LastError = arg.LastError
+	q.feed.NextFetchAt = arg.NextFetchAt
+	q.feed.DisabledAt = arg.DisabledAt
+	return nil
+}
+
+func (q *fakeQuerier) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	q.feed.ConsecutiveFailures = 0
+	q.feed.LastError = sql.NullString{}
+	return nil
+}
+
+func (q *fakeQuerier) EnableFeed(ctx context.Context, id uuid.UUID) error {
+	q.feed.ConsecutiveFailures = 0
+	q.feed.NextFetchAt = sql.NullTime{}
+	q.feed.DisabledAt = sql.NullTime{}
+	return nil
+}
+
+func (q *fakeQuerier) SetFeedCacheValidators(ctx context.Context, arg database.SetFeedCacheValidatorsParams) error {
+	q.feed.Etag = arg.Etag
+	q.feed.LastModified = arg.LastModified
+	return nil
+}
+
+func (q *fakeQuerier) UpdateFeedMetadata(ctx context.Context, arg database.UpdateFeedMetadataParams) error {
+	q.feed.Title = arg.Title
+	q.feed.Description = arg.Description
+	q.feed.SiteUrl = arg.SiteUrl
+	q.feed.ImageUrl = arg.ImageUrl
+	q.feed.Language = arg.Language
+	return nil
+}
+
+func (q *fakeQuerier) CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) error {
+	q.fetches = append(q.fetches, arg)
+	return nil
+}
+
+func (q *fakeQuerier) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	key := arg.FeedID.String() + " " + arg.Guid
+	post, ok := q.posts[key]
+	if !ok {
+		post.ID = arg.ID
+		post.CreatedAt = arg.CreatedAt
+		post.FeedID = arg.FeedID
+		post.Guid = arg.Guid
+	}
+	post.UpdatedAt = arg.UpdatedAt
+	post.Title = arg.Title
+	post.Url = arg.Url
+	post.Description = arg.Description
+	post.PublishedAt = arg.PublishedAt
+	post.PublishedAtInferred = arg.PublishedAtInferred
+	post.Content = arg.Content
+	post.Author = arg.Author
+	q.posts[key] = post
+	return post, nil
+}
+
+func (q *fakeQuerier) CreatePostCategory(ctx context.Context, arg database.CreatePostCategoryParams) error {
+	q.categories[arg.PostID] = append(q.categories[arg.PostID], arg.Name)
+	return nil
+}
+
+func (q *fakeQuerier) CreateEnclosure(ctx context.Context, arg database.CreateEnclosureParams) error {
+	q.enclosures[arg.PostID] = append(q.enclosures[arg.PostID], arg)
+	return nil
+}
+
+func (q *fakeQuerier) SetPostArticle(ctx context.Context, arg database.SetPostArticleParams) error {
+	q.articles[arg.ID] = arg.Article.String
+	for key, post := range q.posts {
+		if post.ID == arg.ID {
+			post.Article = arg.Article
+			q.posts[key] = post
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) RecordPostArticleFailure(ctx context.Context, arg database.RecordPostArticleFailureParams) error {
+	for key, post := range q.posts {
+		if post.ID == arg.ID {
+			post.ArticleAttempts = arg.ArticleAttempts
+			post.ArticleNextFetchAt = arg.ArticleNextFetchAt
+			q.posts[key] = post
+		}
+	}
+	return nil
+}
+
+func (q *fakeQuerier) GetPostsWithoutArticle(ctx context.Context, arg database.GetPostsWithoutArticleParams) ([]database.GetPostsWithoutArticleRow, error) {
+	var rows []database.GetPostsWithoutArticleRow
+	if !q.feed.FetchFullArticle {
+		return rows, nil
+	}
+	for _, post := range q.posts {
+		if post.FeedID != q.feed.ID || post.Article.Valid || post.Url == "" || post.ArticleAttempts >= arg.MaxAttempts ||
+			(post.ArticleNextFetchAt.Valid && post.ArticleNextFetchAt.Time.After(arg.Now)) {
+			continue
+		}
+		rows = append(rows, database.GetPostsWithoutArticleRow{
+			ID:                 post.ID,
+			FeedID:             post.FeedID,
+			Url:                post.Url,
+			ArticleAttempts:    post.ArticleAttempts,
+			ArticleNextFetchAt: post.ArticleNextFetchAt,
+			FeedUrl:            q.feed.Url,
+		})
+		if len(rows) == int(arg.PostLimit) {
+			break
+		}
+	}
+	return rows, nil
+}
+
+func (q *fakeQuerier) post(t *testing.T, guid string) database.Post {
+	t.Helper()
+	post, ok := q.posts[q.feed.ID.String()+" "+guid]
+	if !ok {
+		t.Fatalf("post %s was not saved", guid)
+	}
+	return post
+}
+
+func newTestState(t *testing.T, feedURL string) (*state, *fakeQuerier) {
+	t.Helper()
+	q := newFakeQuerier(database.Feed{
+		ID:   uuid.New(),
+		Name: "Example Blog",
+		Url:  feedURL,
+		Kind: feedKindFeed,
+	})
+	return &state{
+		cfg:     &config.Config{},
+		db:      q,
+		hosts:   newHostLimiter(time.Millisecond),
+		fetcher: newHTTPFetcher(nil, 0),
+	}, q
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestScrapeNextFeedSavesPosts(t *testing.T) {
+	feed := readFixture(t, "rss.xml")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(feed)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	scrapeNextFeed(s)
+
+	if len(q.posts) != 2 {
+		t.Fatalf("saved %d posts, want 2", len(q.posts))
+	}
+	first := q.post(t, "https://example.com/posts/first")
+	if first.Title != "First & foremost" {
+		t.Errorf("title = %q, want %q", first.Title, "First & foremost")
+	}
+	if want := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC); !first.PublishedAt.Equal(want) || first.PublishedAtInferred {
+		t.Errorf("published at = %v (inferred %v), want %v", first.PublishedAt, first.PublishedAtInferred, want)
+	}
+	if got := q.categories[first.ID]; len(got) != 1 || got[0] != "news" {
+		t.Errorf("categories = %v, want [news]", got)
+	}
+	if got := q.enclosures[first.ID]; len(got) != 1 || got[0].Url != "https://example.com/audio/first.mp3" {
+		t.Errorf("enclosures = %v, want first.mp3", got)
+	}
+	if second := q.post(t, "https://example.com/posts/second"); !second.PublishedAtInferred {
+		t.Errorf("published at of undated post was not marked inferred")
+	}
+
+	if q.feed.Title != "Example Blog" || q.feed.SiteUrl != "https://example.com/" || q.feed.Language != "en-us" {
+		t.Errorf("metadata = %q, %q, %q, want the channel title, link and language", q.feed.Title, q.feed.SiteUrl, q.feed.Language)
+	}
+	if want := server.URL + "/logo.png"; q.feed.ImageUrl != want {
+		t.Errorf("image url = %q, want %q", q.feed.ImageUrl, want)
+	}
+	if q.feed.Etag.String != `"v1"` {
+		t.Errorf("stored etag = %q, want %q", q.feed.Etag.String, `"v1"`)
+	}
+	if len(q.fetches) != 1 {
+		t.Fatalf("recorded %d fetches, want 1", len(q.fetches))
+	}
+	if fetch := q.fetches[0]; !fetch.Succeeded || fetch.StatusCode.Int32 != http.StatusOK || fetch.ItemCount != 2 {
+		t.Errorf("fetch = %+v, want successful 200 with 2 items", fetch)
+	}
+}
+
+func TestScrapeNextFeedUpdatesExistingPosts(t *testing.T) {
+	feed := readFixture(t, "rss.xml")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write(feed)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	scrapeNextFeed(s)
+	first := q.post(t, "https://example.com/posts/first")
+
+	feed = []byte(strings.Replace(string(feed), "The first post.", "The first post, edited.", 1))
+	scrapeNextFeed(s)
+
+	if len(q.posts) != 2 {
+		t.Fatalf("saved %d posts, want 2", len(q.posts))
+	}
+	updated := q.post(t, "https://example.com/posts/first")
+	if updated.ID != first.ID {
+		t.Errorf("post id changed from %v to %v", first.ID, updated.ID)
+	}
+	if updated.Description != "The first post, edited." {
+		t.Errorf("description = %q, want the edited description", updated.Description)
+	}
+}
+
+func TestScrapeNextFeedNotModified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != `"v1"` {
+			t.Errorf("If-None-Match = %q, want %q", r.Header.Get("If-None-Match"), `"v1"`)
+		}
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	q.feed.Etag = sql.NullString{String: `"v1"`, Valid: true}
+	scrapeNextFeed(s)
+
+	if len(q.posts) != 0 {
+		t.Errorf("saved %d posts, want 0", len(q.posts))
+	}
+	if len(q.fetches) != 1 || !q.fetches[0].Succeeded || q.fetches[0].StatusCode.Int32 != http.StatusNotModified {
+		t.Errorf("fetches = %+v, want one successful 304", q.fetches)
+	}
+}
+
+func TestScrapeNextFeedPermanentRedirect(t *testing.T) {
+	feed := readFixture(t, "rss.xml")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old.xml", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new.xml", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write(feed)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/old.xml")
+	scrapeNextFeed(s)
+
+	if want := server.URL + "/new.xml"; q.feed.Url != want {
+		t.Errorf("feed url = %q, want %q", q.feed.Url, want)
+	}
+	if len(q.posts) != 2 {
+		t.Errorf("saved %d posts, want 2", len(q.posts))
+	}
+}
+
+func TestScrapeNextFeedPermanentRedirectToExistingFeed(t *testing.T) {
+	feed := readFixture(t, "rss.xml")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old.xml", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new.xml", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write(feed)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/old.xml")
+	existing := database.Feed{ID: uuid.New(), Name: "Example Blog (new)", Url: server.URL + "/new.xml", Kind: feedKindFeed}
+	q.otherFeeds = append(q.otherFeeds, existing)
+
+	oldUser, sharedUser := uuid.New(), uuid.New()
+	q.follows[q.feed.ID] = []uuid.UUID{oldUser, sharedUser}
+	q.follows[existing.ID] = []uuid.UUID{sharedUser}
+
+	// The old feed has a post only it knows about, and one the existing feed has too.
+	for _, p := range []database.Post{
+		{ID: uuid.New(), FeedID: q.feed.ID, Guid: "old-only", Title: "Old only"},
+		{ID: uuid.New(), FeedID: q.feed.ID, Guid: "https://example.com/posts/first", Title: "Old copy"},
+		{ID: uuid.New(), FeedID: existing.ID, Guid: "https://example.com/posts/first", Title: "Existing copy"},
+	} {
+		q.posts[p.FeedID.String()+" "+p.Guid] = p
+	}
+
+	scrapeNextFeed(s)
+
+	if q.feed.Url != server.URL+"/old.xml" {
+		t.Errorf("old feed url = %q, want it unchanged", q.feed.Url)
+	}
+	if !q.feed.GoneAt.Valid {
+		t.Errorf("old feed was not marked gone")
+	}
+	if _, ok := q.posts[existing.ID.String()+" old-only"]; !ok {
+		t.Errorf("post only the old feed had was not moved to the existing feed")
+	}
+	if kept, ok := q.posts[q.feed.ID.String()+" https://example.com/posts/first"]; !ok || kept.Title != "Old copy" {
+		t.Errorf("conflicting post of the old feed = %+v, want it kept", kept)
+	}
+	if _, ok := q.posts[existing.ID.String()+" https://example.com/posts/second"]; !ok {
+		t.Errorf("fetched posts were not saved to the existing feed")
+	}
+	if got := q.follows[existing.ID]; len(got) != 2 || !slices.Contains(got, oldUser) {
+		t.Errorf("followers of existing feed = %v, want both users", got)
+	}
+	if got := q.follows[q.feed.ID]; len(got) != 0 {
+		t.Errorf("followers of old feed = %v, want none", got)
+	}
+}
+
+func TestScrapeNextFeedKeepsCredentialsOnHost(t *testing.T) {
+	feed := readFixture(t, "rss.xml")
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{"Authorization", "Cookie", "X-Api-Key"} {
+			if value := r.Header.Get(name); value != "" {
+				t.Errorf("%s = %q sent to another host", name, value)
+			}
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write(feed)
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("X-Api-Key = %q, want the stored header", r.Header.Get("X-Api-Key"))
+		}
+		http.Redirect(w, r, other.URL+"/feed.xml", http.StatusMovedPermanently)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	s.cfg.CredentialsKey = base64.StdEncoding.EncodeToString(make([]byte, 32))
+	err := saveFeedCredentials(s, q.feed.ID, feedCredentials{
+		BearerToken: "token",
+		Cookie:      "session=1",
+		Headers:     map[string]string{"X-Api-Key": "secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scrapeNextFeed(s)
+
+	if q.feed.Url != server.URL+"/feed.xml" {
+		t.Errorf("feed url = %q, want the credentialed feed to keep its url", q.feed.Url)
+	}
+	if len(q.posts) != 2 {
+		t.Errorf("saved %d posts, want 2", len(q.posts))
+	}
+}
+
+func TestHandlerAddFeedRefusesCredentialsForDiscoveredFeedOnOtherHost(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request for %s sent to the other host", r.URL)
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" href="` + other.URL + `/feed.xml"></head></html>`))
+	}))
+	defer server.Close()
+
+	s, _ := newTestState(t, server.URL)
+	err := handlerAddFeed(s, command{Name: "addfeed", Args: []string{"--bearer", "token", server.URL}}, database.User{ID: uuid.New()})
+	if err == nil || !strings.Contains(err.Error(), "is on another host") {
+		t.Fatalf("handlerAddFeed error = %v, want the discovered feed refused", err)
+	}
+}
+
+func TestLoadFeedCredentialsWithoutKey(t *testing.T) {
+	s, q := newTestState(t, "https://example.com/feed.xml")
+	q.credentials[q.feed.ID] = "sealed with a lost key"
+
+	if _, err := loadFeedCredentials(s, q.feed.ID); err == nil {
+		t.Fatal("loadFeedCredentials succeeded, want an error for the missing key")
+	}
+	if s.cfg.CredentialsKey != "" {
+		t.Errorf("credentials key = %q, want no key generated on load", s.cfg.CredentialsKey)
+	}
+}
+
+func TestScrapeNextFeedGone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	scrapeNextFeed(s)
+
+	if !q.feed.GoneAt.Valid {
+		t.Errorf("feed was not marked gone")
+	}
+	if len(q.failures) != 0 {
+		t.Errorf("recorded %d failures, want 0", len(q.failures))
+	}
+}
+
+func TestScrapeNextFeedRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	before := time.Now().UTC()
+	scrapeNextFeed(s)
+
+	if len(q.deferrals) != 1 {
+		t.Fatalf("recorded %d deferrals, want 1", len(q.deferrals))
+	}
+	if until := q.deferrals[0].NextFetchAt.Time; until.Before(before.Add(2*time.Minute)) || until.After(time.Now().UTC().Add(2*time.Minute)) {
+		t.Errorf("deferred until %v, want about 2 minutes from now", until)
+	}
+	if len(q.failures) != 0 {
+		t.Errorf("recorded %d failures, want 0", len(q.failures))
+	}
+
+	err := s.hosts.wait(context.Background(), hostOf(q.feed.Url))
+	if _, ok := err.(*hostDeferredError); !ok {
+		t.Errorf("host wait err = %v, want *hostDeferredError", err)
+	}
+}
+
+func TestScrapeNextFeedRetryAfterZero(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	scrapeNextFeed(s)
+
+	if len(q.failures) != 0 {
+		t.Errorf("recorded %d failures, want 0", len(q.failures))
+	}
+	if len(q.deferrals) != 1 {
+		t.Fatalf("recorded %d deferrals, want 1", len(q.deferrals))
+	}
+	if until := q.deferrals[0].NextFetchAt.Time; until.After(time.Now().UTC().Add(time.Second)) {
+		t.Errorf("deferred until %v, want a retry right away", until)
+	}
+}
+
+func TestScrapeNextFeedDeferredHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request sent to a deferred host")
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	until := time.Now().UTC().Add(time.Hour)
+	s.hosts.deferHost(hostOf(q.feed.Url), until)
+	scrapeNextFeed(s)
+
+	if q.feed.LastFetchedAt.Valid {
+		t.Errorf("deferred feed was marked fetched")
+	}
+	if len(q.deferrals) != 1 || !q.deferrals[0].NextFetchAt.Time.Equal(until) {
+		t.Errorf("deferrals = %+v, want one until %v", q.deferrals, until)
+	}
+	if len(q.fetches) != 0 {
+		t.Errorf("recorded %d fetches, want 0", len(q.fetches))
+	}
+}
+
+func TestScrapeNextFeedRecordsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	s.cfg.MaxFeedFailures = 2
+	scrapeNextFeed(s)
+
+	if q.feed.ConsecutiveFailures != 1 || q.feed.DisabledAt.Valid {
+		t.Errorf("after one failure: failures = %d, disabled = %v", q.feed.ConsecutiveFailures, q.feed.DisabledAt.Valid)
+	}
+	if len(q.fetches) != 1 || q.fetches[0].Succeeded || q.fetches[0].StatusCode.Int32 != http.StatusInternalServerError {
+		t.Errorf("fetches = %+v, want one failed 500", q.fetches)
+	}
+
+	scrapeNextFeed(s)
+	if q.feed.ConsecutiveFailures != 2 || !q.feed.DisabledAt.Valid {
+		t.Errorf("after two failures: failures = %d, disabled = %v", q.feed.ConsecutiveFailures, q.feed.DisabledAt.Valid)
+	}
+}
+
+func TestScrapeNextFeedFullArticle(t *testing.T) {
+	paragraph := strings.Repeat("The complete text of the article, which the feed only summarises. ", 8)
+	mux := http.NewServeMux()
+	var server *httptest.Server
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss version="2.0"><channel><title>Example Blog</title><item>
+			<title>Truncated</title>
+			<link>` + server.URL + `/posts/truncated</link>
+			<description>The complete text...</description>
+		</item></channel></rss>`))
+	})
+	mux.HandleFunc("/posts/truncated", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "secret" {
+			t.Errorf("X-Api-Key = %q, want the feed's header", r.Header.Get("X-Api-Key"))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><body>
+			<nav class="menu"><a href="/">Home</a></nav>
+			<div class="post-content"><p>` + paragraph + `</p><p>` + paragraph + `</p></div>
+			<div id="sidebar"><p>Other posts you might like to read next.</p></div>
+		</body></html>`))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	q.feed.FetchFullArticle = true
+	s.cfg.CredentialsKey = base64.StdEncoding.EncodeToString(make([]byte, 32))
+	if err := saveFeedCredentials(s, q.feed.ID, feedCredentials{Headers: map[string]string{"X-Api-Key": "secret"}}); err != nil {
+		t.Fatal(err)
+	}
+	scrapeNextFeed(s)
+
+	post := q.post(t, server.URL+"/posts/truncated")
+	if _, ok := q.articles[post.ID]; ok {
+		t.Fatalf("article was fetched while aggregating the feed")
+	}
+	fetchArticles(s)
+
+	article := q.articles[post.ID]
+	if !strings.Contains(article, "The complete text of the article") {
+		t.Errorf("article = %q, want the extracted body", article)
+	}
+	if strings.Contains(article, "Home") || strings.Contains(article, "Other posts") {
+		t.Errorf("article = %q, want navigation and sidebar removed", article)
+	}
+}
+
+func TestFetchArticlesRetriesFailures(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	q.feed.FetchFullArticle = true
+	q.posts[q.feed.ID.String()+" broken"] = database.Post{ID: uuid.New(), FeedID: q.feed.ID, Guid: "broken", Url: server.URL + "/posts/broken"}
+	fetchArticles(s)
+
+	post := q.post(t, "broken")
+	if post.Article.Valid {
+		t.Errorf("article = %q, want NULL after a failure", post.Article.String)
+	}
+	if post.ArticleAttempts != 1 || !post.ArticleNextFetchAt.Valid || !post.ArticleNextFetchAt.Time.After(time.Now().UTC()) {
+		t.Errorf("attempts = %d, next fetch at = %v, want one attempt and a later retry", post.ArticleAttempts, post.ArticleNextFetchAt)
+	}
+
+	fetchArticles(s)
+	if requests != 1 {
+		t.Errorf("article requested %d times, want no retry before the backoff ends", requests)
+	}
+}
+
+func TestScrapeNextFeedScrapesPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Example News</title></head><body>
+			<ul class="news">
+				<li class="entry">
+					<h2><a href="/news/1">Launch day</a></h2>
+					<time datetime="2024-03-01T10:00:00Z">March 1</time>
+					<div class="teaser"><p>We <em>launched</em>.</p></div>
+				</li>
+				<li class="entry">
+					<h2><a href="https://other.example.com/news/2">Follow-up</a></h2>
+				</li>
+			</ul>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/news")
+	q.feed.Kind = feedKindScrape
+	q.feed.ScrapeRules = sql.NullString{
+		String: `{"item":"li.entry","title":"h2","link":"h2 a","date":"time","summary":".teaser"}`,
+		Valid:  true,
+	}
+	scrapeNextFeed(s)
+
+	if len(q.posts) != 2 {
+		t.Fatalf("saved %d posts, want 2", len(q.posts))
+	}
+	first := q.post(t, server.URL+"/news/1")
+	if first.Title != "Launch day" || first.Description != "<p>We <em>launched</em>.</p>" {
+		t.Errorf("post = %q %q", first.Title, first.Description)
+	}
+	if want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC); !first.PublishedAt.Equal(want) {
+		t.Errorf("published at = %v, want %v", first.PublishedAt, want)
+	}
+	if second := q.post(t, "https://other.example.com/news/2"); second.Title != "Follow-up" {
+		t.Errorf("title = %q, want %q", second.Title, "Follow-up")
+	}
+	if q.feed.Title != "Example News" {
+		t.Errorf("feed title = %q, want the page title", q.feed.Title)
+	}
+}
+
+func TestScrapeNextFeedKeepsScrapedText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><body>
+			<div class="entry">
+				<a href="/posts/1">Escaping &amp;lt;b&amp;gt; in HTML</a>
+				<p class="summary">Write <code>&lt;b&gt;</code> as <code>&amp;lt;b&amp;gt;</code></p>
+			</div>
+			<div class="entry">
+				<h3 class="headline">Headline</h3>
+				<a href="/posts/2">Read more</a>
+			</div>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/posts")
+	q.feed.Kind = feedKindScrape
+	q.feed.ScrapeRules = sql.NullString{
+		String: `{"item":".entry","title":".headline","link":"a","summary":".summary"}`,
+		Valid:  true,
+	}
+	scrapeNextFeed(s)
+
+	first := q.post(t, server.URL+"/posts/1")
+	if first.Title != "Escaping &lt;b&gt; in HTML" {
+		t.Errorf("title = %q, want the link text when the title selector matches nothing", first.Title)
+	}
+	if want := "Write <code>&lt;b&gt;</code> as <code>&amp;lt;b&amp;gt;</code>"; first.Description != want {
+		t.Errorf("description = %q, want %q", first.Description, want)
+	}
+	if second := q.post(t, server.URL+"/posts/2"); second.Title != "Headline" {
+		t.Errorf("title = %q, want the matched title", second.Title)
+	}
+}
+
+func TestScrapeNextFeedScrapeWithoutMatches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><p>Redesigned!</p></body></html>`))
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/news")
+	q.feed.Kind = feedKindScrape
+	q.feed.ScrapeRules = sql.NullString{String: `{"item":"li.entry"}`, Valid: true}
+	scrapeNextFeed(s)
+
+	if q.feed.ConsecutiveFailures != 1 || !strings.Contains(q.feed.LastError.String, "li.entry") {
+		t.Errorf("failures = %d, last error = %q, want a failure naming the selector", q.feed.ConsecutiveFailures, q.feed.LastError.String)
+	}
+}
+
+func TestScrapeNextFeedMapsJSONAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"releases": [
+			{"id": 101, "name": "v1.2.0", "links": {"html": "/releases/v1.2.0"}, "published": 1709287200, "notes": "Bug fixes"},
+			{"id": 100, "name": "v1.1.0", "links": {"html": "/releases/v1.1.0"}, "published": "2024-02-01T09:00:00Z"},
+			{"id": 99, "name": "v1.0.1", "links": {"html": "/releases/v1.0.1"}, "published": 1706000000.5},
+			{"id": 98, "name": "v1.0.0", "links": {"html": "/releases/v1.0.0"}, "published": 1705000000000}
+		]}}`))
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/api/releases")
+	q.feed.Kind = feedKindJSON
+	q.feed.JsonMapping = sql.NullString{
+		String: `{"items":"data.releases","id":"id","title":"name","url":"links.html","date":"published","body":"notes"}`,
+		Valid:  true,
+	}
+	scrapeNextFeed(s)
+
+	if len(q.posts) != 4 {
+		t.Fatalf("saved %d posts, want 4", len(q.posts))
+	}
+	latest := q.post(t, "101")
+	if latest.Title != "v1.2.0" || latest.Url != server.URL+"/releases/v1.2.0" || latest.Description != "Bug fixes" {
+		t.Errorf("post = %q %q %q", latest.Title, latest.Url, latest.Description)
+	}
+	if want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC); !latest.PublishedAt.Equal(want) {
+		t.Errorf("published at = %v, want %v", latest.PublishedAt, want)
+	}
+	if older := q.post(t, "100"); !older.PublishedAt.Equal(time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("published at = %v, want 2024-02-01T09:00:00Z", older.PublishedAt)
+	}
+	if fractional := q.post(t, "99"); !fractional.PublishedAt.Equal(time.Unix(1706000000, 5e8).UTC()) || fractional.PublishedAtInferred {
+		t.Errorf("published at = %v (inferred %v), want the fractional epoch", fractional.PublishedAt, fractional.PublishedAtInferred)
+	}
+	if millis := q.post(t, "98"); !millis.PublishedAt.Equal(time.Unix(1705000000, 0).UTC()) {
+		t.Errorf("published at = %v, want the epoch in milliseconds", millis.PublishedAt)
+	}
+}
+
+func TestScrapeNextFeedKeepsMappedJSONText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id": "1", "name": "x &lt; y", "url": "/issues/1", "notes": "Use &amp;amp; for &amp;"}]`))
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/api/issues")
+	q.feed.Kind = feedKindJSON
+	q.feed.JsonMapping = sql.NullString{
+		String: `{"items":"","id":"id","title":"name","url":"url","body":"notes"}`,
+		Valid:  true,
+	}
+	scrapeNextFeed(s)
+
+	post := q.post(t, "1")
+	if post.Title != "x &lt; y" || post.Description != "Use &amp;amp; for &amp;" {
+		t.Errorf("post = %q %q, want the JSON values unchanged", post.Title, post.Description)
+	}
+}
+
+func TestScrapeNextFeedReadsFileURL(t *testing.T) {
+	path, err := filepath.Abs(filepath.Join("testdata", "rss.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, q := newTestState(t, "file://"+filepath.ToSlash(path))
+	scrapeNextFeed(s)
+
+	if len(q.posts) != 2 {
+		t.Fatalf("saved %d posts, want 2", len(q.posts))
+	}
+	if !q.feed.LastModified.Valid {
+		t.Fatalf("no last modified time stored for file feed")
+	}
+
+	scrapeNextFeed(s)
+	if fetch := q.fetches[len(q.fetches)-1]; fetch.StatusCode.Int32 != http.StatusNotModified {
+		t.Errorf("second fetch status = %d, want %d", fetch.StatusCode.Int32, http.StatusNotModified)
+	}
+}
+
+func TestRemoteFileURLsAreRefused(t *testing.T) {
+	const remote = "file://fileserver/share/feed.xml"
+
+	s, q := newTestState(t, remote)
+	err := handlerAddFeed(s, command{Name: "addfeed", Args: []string{"Share", remote}}, database.User{ID: uuid.New()})
+	if err == nil || !strings.Contains(err.Error(), "remote host") {
+		t.Errorf("handlerAddFeed error = %v, want the remote host refused", err)
+	}
+
+	scrapeNextFeed(s)
+	if q.feed.ConsecutiveFailures != 1 || !strings.Contains(q.feed.LastError.String, "remote host") {
+		t.Errorf("failures = %d, last error = %q, want the remote host refused", q.feed.ConsecutiveFailures, q.feed.LastError.String)
+	}
+}
+
+func TestScrapeNextFeedRefusesRedirectToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.xml")
+	if err := os.WriteFile(path, readFixture(t, "rss.xml"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "file://"+filepath.ToSlash(path), http.StatusMovedPermanently)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	scrapeNextFeed(s)
+
+	if len(q.posts) != 0 {
+		t.Errorf("saved %d posts from a local file", len(q.posts))
+	}
+	if q.feed.Url != server.URL+"/feed.xml" || q.feed.ConsecutiveFailures != 1 {
+		t.Errorf("feed url = %q, failures = %d, want the url kept and a failure", q.feed.Url, q.feed.ConsecutiveFailures)
+	}
+}
+
+func TestFetchArticlesSkipsFileURLs(t *testing.T) {
+	s, q := newTestState(t, "https://example.com/feed.xml")
+	s.fetcher = nil
+	q.feed.FetchFullArticle = true
+	q.posts[q.feed.ID.String()+" local"] = database.Post{ID: uuid.New(), FeedID: q.feed.ID, Guid: "local", Url: "file:///etc/passwd"}
+	fetchArticles(s)
+
+	if post := q.post(t, "local"); post.Article.Valid || post.ArticleAttempts != maxArticleAttempts {
+		t.Errorf("article = %v, attempts = %d, want no article and no further attempts", post.Article, post.ArticleAttempts)
+	}
+}
+
+func TestHandlerIngestRequiresOwnership(t *testing.T) {
+	s, q := newTestState(t, "https://example.com/feed.xml")
+	q.feed.UserID = uuid.New()
+
+	err := handlerIngest(s, command{Name: "ingest", Args: []string{q.feed.Name}}, database.User{ID: uuid.New(), Name: "other"})
+	if err == nil || !strings.Contains(err.Error(), "another user") {
+		t.Errorf("err = %v, want the feed of another user refused", err)
+	}
+	if len(q.posts) != 0 {
+		t.Errorf("saved %d posts into the feed of another user", len(q.posts))
+	}
+}
+
+func TestIngestFeed(t *testing.T) {
+	s, q := newTestState(t, "https://example.com/feed.xml")
+	s.fetcher = nil
+	q.feed.FetchFullArticle = true
+
+	items, saved, err := ingestFeed(s, q.feed, strings.NewReader(string(readFixture(t, "rss.xml"))))
+	if err != nil {
+		t.Fatalf("ingestFeed failed: %v", err)
+	}
+	if items != 2 || saved != 2 || len(q.posts) != 2 {
+		t.Errorf("ingested %d items, saved %d posts (%d stored), want 2", items, saved, len(q.posts))
+	}
+
+	_, saved, err = ingestFeed(s, q.feed, strings.NewReader(string(readFixture(t, "rss.xml"))))
+	if err != nil {
+		t.Fatalf("ingestFeed failed: %v", err)
+	}
+	if saved != 0 || len(q.posts) != 2 {
+		t.Errorf("ingesting the same document again saved %d posts (%d stored), want 0", saved, len(q.posts))
+	}
+}
+
+func TestScrapeNextFeedHonorsTTL(t *testing.T) {
+	modified := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if modified && r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`<rss version="2.0"><channel><title>Slow</title><ttl>120</ttl></channel></rss>`))
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	before := time.Now().UTC()
+	scrapeNextFeed(s)
+
+	if !q.feed.UpdateHints.Valid {
+		t.Fatalf("update hints were not stored")
+	}
+	next := q.feed.NextFetchAt.Time
+	if next.Before(before.Add(2*time.Hour)) || next.After(time.Now().UTC().Add(2*time.Hour)) {
+		t.Errorf("next fetch at %v, want two hours from now", next)
+	}
+
+	modified = true
+	q.feed.NextFetchAt = sql.NullTime{}
+	scrapeNextFeed(s)
+	if !q.feed.NextFetchAt.Valid || q.feed.NextFetchAt.Time.Before(before.Add(2*time.Hour)) {
+		t.Errorf("next fetch at %v after a 304, want the stored ttl to apply", q.feed.NextFetchAt)
+	}
+}
+
+func TestHandlerEnableFeed(t *testing.T) {
+	s, q := newTestState(t, "https://example.com/feed.xml")
+	owner := database.User{ID: uuid.New(), Name: "owner"}
+	q.feed.UserID = owner.ID
+	q.feed.ConsecutiveFailures = 10
+	q.feed.DisabledAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	q.feed.GoneAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	cmd := command{Name: "enablefeed", Args: []string{q.feed.Url}}
+
+	if err := handlerEnableFeed(s, cmd, database.User{ID: uuid.New(), Name: "other"}); err == nil {
+		t.Errorf("another user enabled the feed")
+	}
+	if !q.feed.DisabledAt.Valid {
+		t.Fatalf("feed was enabled by another user")
+	}
+
+	if err := handlerEnableFeed(s, cmd, owner); err != nil {
+		t.Fatalf("enablefeed failed: %v", err)
+	}
+	if q.feed.DisabledAt.Valid || q.feed.ConsecutiveFailures != 0 {
+		t.Errorf("feed still disabled after enablefeed")
+	}
+	if !q.feed.GoneAt.Valid {
+		t.Errorf("enablefeed cleared the gone marker")
+	}
+}
+
+func TestScrapeNextFeedRetriesHonorTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"failure", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}},
+		{"throttled", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			s, q := newTestState(t, server.URL+"/feed.xml")
+			q.feed.UpdateHints = sql.NullString{String: `{"interval_seconds":7200}`, Valid: true}
+			before := time.Now().UTC()
+			scrapeNextFeed(s)
+
+			next := q.feed.NextFetchAt.Time
+			if next.Before(before.Add(2*time.Hour)) || next.After(time.Now().UTC().Add(2*time.Hour)) {
+				t.Errorf("next fetch at %v, want the ttl of two hours over the shorter retry", next)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const minArticleLength = 250
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|foot|header|menu|modal|nav|newsletter|pager|popup|promo|related|remark|rss|share|shoutbox|sidebar|social|sponsor|subscribe|tags|tool|widget|^ad-|-ad$`)
+	likelyCandidates   = regexp.MustCompile(`(?i)and|article|body|column|content|entry|hentry|main|page|post|story|text`)
+	positiveWeight     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|story|text|blog`)
+	negativeWeight     = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|modal|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+var strippedElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"iframe":   true,
+	"form":     true,
+	"button":   true,
+	"input":    true,
+	"select":   true,
+	"textarea": true,
+	"nav":      true,
+	"aside":    true,
+	"footer":   true,
+	"svg":      true,
+	"canvas":   true,
+	"template": true,
+}
+
+var keptAttributes = map[string]bool{
+	"href":  true,
+	"src":   true,
+	"alt":   true,
+	"title": true,
+}
+
+func extractArticle(p *page) (string, error) {
+	r, err := charset.NewReader(bytes.NewReader(p.Data), p.ContentType)
+	if err != nil {
+		return "", err
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(p.URL)
+	if err != nil {
+		return "", err
+	}
+
+	removeUnlikely(doc)
+
+	scores := make(map[*html.Node]float64)
+	var score func(n *html.Node)
+	score = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			score(c)
+		}
+		if n.Type != html.ElementNode {
+			return
+		}
+		switch n.Data {
+		case "p", "pre", "td", "blockquote":
+		default:
+			return
+		}
+
+		text := strings.TrimSpace(textContent(n))
+		if len(text) < 25 {
+			return
+		}
+		contentScore := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+
+		parent := n.Parent
+		if parent == nil || parent.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[parent]; !ok {
+			scores[parent] = initialScore(parent)
+		}
+		scores[parent] += contentScore
+
+		if grandparent := parent.Parent; grandparent != nil && grandparent.Type == html.ElementNode {
+			if _, ok := scores[grandparent]; !ok {
+				scores[grandparent] = initialScore(grandparent)
+			}
+			scores[grandparent] += contentScore / 2
+		}
+	}
+	score(doc)
+
+	// Candidates are compared in document order, so ties go to the first one.
+	var best *html.Node
+	bestScore := 0.0
+	var pick func(n *html.Node)
+	pick = func(n *html.Node) {
+		if s, ok := scores[n]; ok {
+			s *= 1 - linkDensity(n)
+			if best == nil || s > bestScore {
+				best = n
+				bestScore = s
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			pick(c)
+		}
+	}
+	pick(doc)
+	if best == nil || len(strings.TrimSpace(textContent(best))) < minArticleLength {
+		return "", errors.New("no article content found")
+	}
+
+	clean(best, base)
+
+	var buf bytes.Buffer
+	for c := best.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func removeUnlikely(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode {
+			n.RemoveChild(c)
+		} else if c.Type == html.ElementNode && isUnlikely(c) {
+			n.RemoveChild(c)
+		} else {
+			removeUnlikely(c)
+		}
+		c = next
+	}
+}
+
+func isUnlikely(n *html.Node) bool {
+	if strippedElements[n.Data] {
+		return true
+	}
+	switch n.Data {
+	case "html", "body", "article", "main":
+		return false
+	}
+	match := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidates.MatchString(match) && !likelyCandidates.MatchString(match)
+}
+
+func initialScore(n *html.Node) float64 {
+	var s float64
+	switch n.Data {
+	case "article":
+		s = 10
+	case "div", "main":
+		s = 5
+	case "pre", "td", "blockquote":
+		s = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		s = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		s = -5
+	}
+
+	for _, value := range []string{attr(n, "class"), attr(n, "id")} {
+		if value == "" {
+			continue
+		}
+		if negativeWeight.MatchString(value) {
+			s -= 25
+		}
+		if positiveWeight.MatchString(value) {
+			s += 25
+		}
+	}
+	return s
+}
+
+func linkDensity(n *html.Node) float64 {
+	textLength := len(textContent(n))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "a" {
+			linkLength += len(textContent(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return float64(linkLength) / float64(textLength)
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+func clean(n *html.Node, base *url.URL) {
+	if n.Type == html.ElementNode {
+		var attrs []html.Attribute
+		for _, a := range n.Attr {
+			if !keptAttributes[a.Key] {
+				continue
+			}
+			if a.Key == "href" || a.Key == "src" {
+				if u, err := base.Parse(strings.TrimSpace(a.Val)); err == nil {
+					a.Val = u.String()
+				}
+			}
+			attrs = append(attrs, a)
+		}
+		n.Attr = attrs
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		clean(c, base)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractArticlePrefersFirstCandidateOnTie(t *testing.T) {
+	paragraph := strings.Repeat("Both sections of this page have exactly the same amount of text ", 5)
+	p := &page{
+		URL:         "https://example.com/post",
+		ContentType: "text/html; charset=utf-8",
+		Data: []byte(`<html><body>
+			<div class="first"><p>First ` + paragraph + `</p></div>
+			<div class="second"><p>Other ` + paragraph + `</p></div>
+		</body></html>`),
+	}
+
+	for i := 0; i < 20; i++ {
+		article, err := extractArticle(p)
+		if err != nil {
+			t.Fatalf("extractArticle failed: %v", err)
+		}
+		if !strings.HasPrefix(article, "<p>First") {
+			t.Fatalf("article = %q, want the first of two equal candidates", article)
+		}
+	}
+}
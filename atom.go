@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+type AtomFeed struct {
+	Lang     string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title    AtomText    `xml:"title"`
+	Subtitle AtomText    `xml:"subtitle"`
+	Links    []AtomLink  `xml:"link"`
+	Logo     string      `xml:"logo"`
+	Icon     string      `xml:"icon"`
+	Entries  []AtomEntry `xml:"entry"`
+
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+type AtomEntry struct {
+	ID         string         `xml:"id"`
+	Title      AtomText       `xml:"title"`
+	Links      []AtomLink     `xml:"link"`
+	Summary    AtomText       `xml:"summary"`
+	Content    AtomText       `xml:"content"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []AtomPerson   `xml:"author"`
+	Categories []AtomCategory `xml:"category"`
+}
+
+type AtomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.InnerXML)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+func parseAtomFeed(decoder *xml.Decoder, root xml.StartElement) (*RSSFeed, error) {
+	atom := &AtomFeed{}
+	if err := decoder.DecodeElement(atom, &root); err != nil {
+		return nil, err
+	}
+
+	feed := &RSSFeed{}
+	feed.Channel.Title = atom.Title.String()
+	feed.Channel.Link = alternateLink(atom.Links)
+	feed.Channel.Description = atom.Subtitle.String()
+	feed.Channel.Language = strings.TrimSpace(atom.Lang)
+	feed.Channel.Hubs, feed.Channel.Self = hubLinks(atom.Links)
+	feed.Channel.UpdatePeriod = atom.UpdatePeriod
+	feed.Channel.UpdateFrequency = atom.UpdateFrequency
+	feed.Channel.Image.URL = strings.TrimSpace(atom.Logo)
+	if feed.Channel.Image.URL == "" {
+		feed.Channel.Image.URL = strings.TrimSpace(atom.Icon)
+	}
+	for _, entry := range atom.Entries {
+		item := RSSItem{
+			Title:       entry.Title.String(),
+			Link:        alternateLink(entry.Links),
+			Description: entry.Summary.String(),
+			PubDate:     strings.TrimSpace(entry.Published),
+			GUID:        strings.TrimSpace(entry.ID),
+			Content:     entry.Content.String(),
+		}
+		var authors []string
+		for _, author := range entry.Authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				authors = append(authors, name)
+			}
+		}
+		item.Author = strings.Join(authors, ", ")
+		for _, category := range entry.Categories {
+			item.Categories = append(item.Categories, category.Term)
+		}
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				item.Enclosures = append(item.Enclosures, RSSEnclosure{
+					URL:    link.Href,
+					Type:   link.Type,
+					Length: link.Length,
+				})
+			}
+		}
+		if item.Description == "" {
+			item.Description = entry.Content.String()
+		}
+		if item.PubDate == "" {
+			item.PubDate = strings.TrimSpace(entry.Updated)
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return feed, nil
+}
+
+func hubLinks(links []AtomLink) ([]string, string) {
+	var hubs []string
+	self := ""
+	for _, link := range links {
+		for _, rel := range strings.Fields(link.Rel) {
+			switch rel {
+			case "hub":
+				hubs = append(hubs, strings.TrimSpace(link.Href))
+			case "self":
+				self = strings.TrimSpace(link.Href)
+			}
+		}
+	}
+	return hubs, self
+}
+
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestParseAtomFeed(t *testing.T) {
+	feed, err := parseFeed(readFixture(t, "atom.xml"), "application/atom+xml")
+	if err != nil {
+		t.Fatalf("parseFeed failed: %v", err)
+	}
+
+	if feed.Channel.Title != "Example Atom" || feed.Channel.Description != "Entries about examples" {
+		t.Errorf("channel = %q / %q", feed.Channel.Title, feed.Channel.Description)
+	}
+	if feed.Channel.Link != "https://example.com/" {
+		t.Errorf("channel link = %q, want the rel=alternate link", feed.Channel.Link)
+	}
+	if feed.Channel.Self != "https://example.com/atom.xml" {
+		t.Errorf("self = %q", feed.Channel.Self)
+	}
+	if feed.Channel.Language != "en" {
+		t.Errorf("language = %q", feed.Channel.Language)
+	}
+	if len(feed.Channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Channel.Item))
+	}
+
+	first := feed.Channel.Item[0]
+	if first.Link != "https://example.com/entries/1" {
+		t.Errorf("first link = %q, want the rel=alternate link", first.Link)
+	}
+	if first.GUID != "tag:example.com,2006:entry-1" {
+		t.Errorf("first guid = %q, want the entry id", first.GUID)
+	}
+	if first.Description != "A short summary." {
+		t.Errorf("first description = %q, want the summary", first.Description)
+	}
+	if first.Content != "<p>The full content.</p>" {
+		t.Errorf("first content = %q", first.Content)
+	}
+	if first.PubDate != "2006-01-02T15:04:05Z" {
+		t.Errorf("first pubDate = %q, want published", first.PubDate)
+	}
+	if first.Author != "Jane Doe" || len(first.Categories) != 1 || first.Categories[0] != "news" {
+		t.Errorf("first author = %q, categories = %v", first.Author, first.Categories)
+	}
+
+	second := feed.Channel.Item[1]
+	if second.Link != "https://example.com/entries/2" {
+		t.Errorf("second link = %q, want the link without rel", second.Link)
+	}
+	if second.GUID != "tag:example.com,2006:entry-2" {
+		t.Errorf("second guid = %q", second.GUID)
+	}
+	if second.Description != "<p>Only content.</p>" {
+		t.Errorf("second description = %q, want the content fallback", second.Description)
+	}
+	if second.PubDate != "2006-01-04T08:30:00Z" {
+		t.Errorf("second pubDate = %q, want the updated fallback", second.PubDate)
+	}
+}
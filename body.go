@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+const defaultMaxFeedBytes = 10 << 20
+
+const acceptEncoding = "gzip, deflate, br"
+
+type feedTooLargeError struct {
+	Limit int64
+}
+
+func (e *feedTooLargeError) Error() string {
+	return fmt.Sprintf("response exceeds maximum size of %d bytes", e.Limit)
+}
+
+func readBody(resp *http.Response, maxBytes int64) ([]byte, error) {
+	return readEncodedBody(resp.Header, resp.ContentLength, resp.Body, maxBytes)
+}
+
+func readEncodedBody(header http.Header, contentLength int64, r io.Reader, maxBytes int64) ([]byte, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxFeedBytes
+	}
+	if contentLength > maxBytes && header.Get("Content-Encoding") == "" {
+		return nil, &feedTooLargeError{Limit: maxBytes}
+	}
+
+	body, err := decodeContentEncoding(header.Get("Content-Encoding"), r)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, &feedTooLargeError{Limit: maxBytes}
+	}
+
+	return data, nil
+}
+
+func decodeContentEncoding(encoding string, body io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.NopCloser(body), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		br := bufio.NewReader(body)
+		header, err := br.Peek(2)
+		if err == nil && isZlibHeader(header) {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case "br":
+		return io.NopCloser(brotli.NewReader(body)), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
+
+// isZlibHeader reports whether a "deflate" body carries the zlib wrapper the
+// spec requires; some servers send raw deflate streams instead.
+func isZlibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func compress(t *testing.T, newWriter func(io.Writer) io.WriteCloser, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	if _, err := io.WriteString(w, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadEncodedBodyEncodings(t *testing.T) {
+	const want = "<rss><channel><title>Encoded</title></channel></rss>"
+
+	tests := []struct {
+		name      string
+		encoding  string
+		newWriter func(io.Writer) io.WriteCloser
+	}{
+		{"identity", "", func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} }},
+		{"gzip", "gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+		{"x-gzip", "x-gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+		{"zlib deflate", "deflate", func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }},
+		{"raw deflate", "deflate", func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		}},
+		{"brotli", "br", func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := compress(t, tt.newWriter, want)
+			header := http.Header{"Content-Encoding": {tt.encoding}}
+			got, err := readEncodedBody(header, int64(len(body)), bytes.NewReader(body), 1024)
+			if err != nil {
+				t.Fatalf("readEncodedBody failed: %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("body = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestReadEncodedBodyLimits(t *testing.T) {
+	large := strings.Repeat("a", 4096)
+	gzipped := compress(t, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }, large)
+
+	limit := int64(len(large))
+
+	tests := []struct {
+		name          string
+		encoding      string
+		contentLength int64
+		body          []byte
+		limit         int64
+		wantErr       bool
+	}{
+		{"within the limit", "", int64(len(large)), []byte(large), limit, false},
+		{"content length precheck", "", 1 << 30, []byte("short"), limit, true},
+		{"unknown length over the limit", "", -1, []byte(large), limit - 1, true},
+		{"compressed length is not prechecked", "gzip", 1 << 30, gzipped, limit, false},
+		{"decompressed size over the limit", "gzip", int64(len(gzipped)), gzipped, limit - 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.encoding != "" {
+				header.Set("Content-Encoding", tt.encoding)
+			}
+			_, err := readEncodedBody(header, tt.contentLength, bytes.NewReader(tt.body), tt.limit)
+			var tooLarge *feedTooLargeError
+			if tt.wantErr {
+				if !errors.As(err, &tooLarge) || tooLarge.Limit != tt.limit {
+					t.Fatalf("readEncodedBody error = %v, want a feedTooLargeError with limit %d", err, tt.limit)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readEncodedBody failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestReadEncodedBodyUnsupportedEncoding(t *testing.T) {
+	header := http.Header{"Content-Encoding": {"compress"}}
+	if _, err := readEncodedBody(header, -1, strings.NewReader("data"), 1024); err == nil {
+		t.Fatal("readEncodedBody succeeded, want an error")
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -2,16 +2,25 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"github.com/timpinoy/bd-aggregator/internal/database"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	full := fs.Bool("full", false, "show the extracted full article when available")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil || len(args) > 1 {
+		return fmt.Errorf("usage: %s [number_of_posts] [--full]", cmd.Name)
+	}
+
 	numberPosts := 2
-	var err error
-	if len(cmd.Args) > 0 {
-		numberPosts, err = strconv.Atoi(cmd.Args[0])
+	if len(args) > 0 {
+		numberPosts, err = strconv.Atoi(args[0])
 		if err != nil {
 			return fmt.Errorf("could not parse number of posts: %v", err)
 		}
@@ -27,12 +36,62 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
 
 	fmt.Printf("Found %d posts for user %s:\n------\n", len(p), user.Name)
 
+	width := terminalWidth()
+
 	for _, post := range p {
 		fmt.Printf("%s\n", post.Title)
-		fmt.Printf("\tURL: %s\n\n", post.Url)
-		fmt.Printf("%s\n\n\n", post.Description)
+		fmt.Printf("\tURL: %s\n", post.Url)
+		if post.Author != "" {
+			fmt.Printf("\tAuthor: %s\n", post.Author)
+		}
+
+		categories, err := s.db.GetCategoriesForPost(context.Background(), post.ID)
+		if err != nil {
+			return fmt.Errorf("retrieving of categories failed: %v", err)
+		}
+		if len(categories) > 0 {
+			fmt.Printf("\tCategories: %s\n", strings.Join(categories, ", "))
+		}
+		fmt.Println()
+		hasArticle := post.Article.Valid && post.Article.String != ""
+		if *full && hasArticle {
+			fmt.Printf("%s\n\n", renderHTML(post.Article.String, width))
+		} else {
+			fmt.Printf("%s\n\n", renderHTML(post.Description, width))
+			if hasArticle {
+				fmt.Printf("\t(full article available, use --full to read it)\n")
+			}
+		}
+
+		enclosures, err := s.db.GetEnclosuresForPost(context.Background(), post.ID)
+		if err != nil {
+			return fmt.Errorf("retrieving of enclosures failed: %v", err)
+		}
+		for _, encl := range enclosures {
+			printEnclosure(encl)
+		}
+		fmt.Println()
 		fmt.Println("=====================================")
 	}
 
 	return nil
 }
+
+func printEnclosure(encl database.Enclosure) {
+	var details []string
+	if encl.MimeType != "" {
+		details = append(details, encl.MimeType)
+	}
+	if encl.Length.Valid {
+		details = append(details, fmt.Sprintf("%d bytes", encl.Length.Int64))
+	}
+	if encl.DurationSeconds.Valid {
+		details = append(details, (time.Duration(encl.DurationSeconds.Int32) * time.Second).String())
+	}
+
+	if len(details) > 0 {
+		fmt.Printf("\t%s: %s (%s)\n", encl.Kind, encl.Url, strings.Join(details, ", "))
+	} else {
+		fmt.Printf("\t%s: %s\n", encl.Kind, encl.Url)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/timpinoy/bd-aggregator/internal/database"
+	"github.com/timpinoy/bd-aggregator/internal/secrets"
+)
+
+type feedCredentials struct {
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"`
+	Cookie      string            `json:"cookie,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+func (c feedCredentials) isEmpty() bool {
+	return c.Username == "" && c.Password == "" && c.BearerToken == "" && c.Cookie == "" && len(c.Headers) == 0
+}
+
+func (c feedCredentials) header() http.Header {
+	header := http.Header{}
+	for name, value := range c.Headers {
+		header.Set(name, value)
+	}
+	if c.Username != "" || c.Password != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Password))
+		header.Set("Authorization", "Basic "+auth)
+	}
+	if c.BearerToken != "" {
+		header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+	if c.Cookie != "" {
+		header.Set("Cookie", c.Cookie)
+	}
+	return header
+}
+
+func loadFeedCredentials(s *state, feedID uuid.UUID) (feedCredentials, error) {
+	var creds feedCredentials
+
+	row, err := s.db.GetFeedCredentials(context.Background(), feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return creds, nil
+	}
+	if err != nil {
+		return creds, err
+	}
+
+	key, err := s.cfg.DecryptionKey()
+	if err != nil {
+		return creds, err
+	}
+	plaintext, err := secrets.Decrypt(key, row.Secret)
+	if err != nil {
+		return creds, fmt.Errorf("decryption of credentials failed: %v", err)
+	}
+	err = json.Unmarshal(plaintext, &creds)
+	return creds, err
+}
+
+func saveFeedCredentials(s *state, feedID uuid.UUID, creds feedCredentials) error {
+	if creds.isEmpty() {
+		return s.db.DeleteFeedCredentials(context.Background(), feedID)
+	}
+
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	key, err := s.cfg.EncryptionKey()
+	if err != nil {
+		return err
+	}
+	secret, err := secrets.Encrypt(key, plaintext)
+	if err != nil {
+		return err
+	}
+
+	return s.db.UpsertFeedCredentials(context.Background(), database.UpsertFeedCredentialsParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		FeedID:    feedID,
+		Secret:    secret,
+	})
+}
+
+type credentialFlags struct {
+	basic     string
+	bearer    string
+	cookie    string
+	headers   headerFlags
+	clearAuth bool
+}
+
+func (f *credentialFlags) register(fs *flag.FlagSet, allowClear bool) {
+	fs.StringVar(&f.basic, "basic", "", "HTTP Basic credentials as user:password")
+	fs.StringVar(&f.bearer, "bearer", "", "bearer token sent in the Authorization header")
+	fs.StringVar(&f.cookie, "cookie", "", "cookie sent with every request")
+	fs.Var(&f.headers, "header", "custom request header as 'Name: value', repeatable")
+	if allowClear {
+		fs.BoolVar(&f.clearAuth, "clear-auth", false, "remove all stored credentials and headers")
+	}
+}
+
+func (f *credentialFlags) apply(creds *feedCredentials) error {
+	if f.clearAuth {
+		*creds = feedCredentials{}
+	}
+	if f.basic != "" {
+		username, password, ok := strings.Cut(f.basic, ":")
+		if !ok {
+			return errors.New("basic credentials must be formatted as user:password")
+		}
+		creds.Username = username
+		creds.Password = password
+		creds.BearerToken = ""
+	}
+	if f.bearer != "" {
+		creds.BearerToken = f.bearer
+		creds.Username = ""
+		creds.Password = ""
+	}
+	if f.cookie != "" {
+		creds.Cookie = f.cookie
+	}
+	for name, value := range f.headers {
+		if creds.Headers == nil {
+			creds.Headers = make(map[string]string)
+		}
+		creds.Headers[name] = value
+	}
+	return nil
+}
+
+func (f *credentialFlags) isSet() bool {
+	return f.clearAuth || f.basic != "" || f.bearer != "" || f.cookie != "" || len(f.headers) > 0
+}
+
+type headerFlags map[string]string
+
+func (h *headerFlags) String() string {
+	return fmt.Sprint(map[string]string(*h))
+}
+
+func (h *headerFlags) Set(value string) error {
+	name, val, ok := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("invalid header %q, expected 'Name: value'", value)
+	}
+	if *h == nil {
+		*h = make(headerFlags)
+	}
+	(*h)[http.CanonicalHeaderKey(name)] = strings.TrimSpace(val)
+	return nil
+}
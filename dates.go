@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var pubDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 January 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 January 2006 15:04:05",
+	"2 Jan 2006",
+	"2 January 2006",
+	"02-Jan-06 15:04:05 -0700",
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2 15:04:05 2006",
+	"January 2, 2006 15:04:05 -0700",
+	"January 2, 2006 15:04 -0700",
+	"January 2, 2006 15:04",
+	"January 2, 2006",
+	"Jan 2, 2006 15:04 -0700",
+	"Jan 2, 2006 15:04",
+	"Jan 2, 2006",
+}
+
+var zoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"HST":  "-1000",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"BST":  "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"MET":  "+0100",
+	"MEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"MSK":  "+0300",
+	"IST":  "+0530",
+	"JST":  "+0900",
+	"KST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+	"NZST": "+1200",
+	"NZDT": "+1300",
+}
+
+var (
+	leadingWeekday = regexp.MustCompile(`^[A-Za-z]+\.?,?\s+`)
+	trailingZone   = regexp.MustCompile(`\s+\(?([A-Za-z]{1,4})\)?$`)
+	numericOffset  = regexp.MustCompile(`[+-]\d{2}:?\d{2}$`)
+	extraSpaces    = regexp.MustCompile(`\s+`)
+	zoneWithOffset = regexp.MustCompile(`(?i)\s*\b(?:GMT|UTC|UT)\s*([+-])(\d{1,2})(?::?(\d{2}))?$`)
+	twelveHourTime = regexp.MustCompile(`\b(\d{1,2}):(\d{2})(:\d{2})?\s*([AaPp])\.?[Mm]\.?`)
+	timeAfterAt    = regexp.MustCompile(`\s+at\s+(\d)`)
+)
+
+func parsePubDate(value string) (time.Time, error) {
+	normalized := normalizePubDate(value)
+	if normalized == "" {
+		return time.Time{}, errors.New("empty publication date")
+	}
+
+	for _, candidate := range []string{normalized, leadingWeekday.ReplaceAllString(normalized, "")} {
+		for _, layout := range pubDateLayouts {
+			if t, err := time.Parse(layout, candidate); err == nil {
+				return t.UTC(), nil
+			}
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized publication date format: %q", value)
+}
+
+func normalizePubDate(value string) string {
+	value = extraSpaces.ReplaceAllString(strings.TrimSpace(value), " ")
+	value = timeAfterAt.ReplaceAllString(value, " $1")
+
+	// Offsets written relative to a zone name, e.g. "GMT+2" or "UTC-05:30".
+	if m := zoneWithOffset.FindStringSubmatch(value); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		value = strings.TrimSuffix(value, m[0]) + fmt.Sprintf(" %s%02d%02d", m[1], hours, minutes)
+	}
+
+	if m := trailingZone.FindStringSubmatchIndex(value); m != nil {
+		zone := strings.ToUpper(value[m[2]:m[3]])
+		if offset, ok := zoneOffsets[zone]; ok {
+			value = value[:m[0]]
+			if !numericOffset.MatchString(value) {
+				value += " " + offset
+			}
+		}
+	}
+
+	return twelveHourTime.ReplaceAllStringFunc(value, to24Hour)
+}
+
+func to24Hour(value string) string {
+	m := twelveHourTime.FindStringSubmatch(value)
+	hour, err := strconv.Atoi(m[1])
+	if err != nil || hour < 1 || hour > 12 {
+		return value
+	}
+	hour %= 12
+	if strings.EqualFold(m[4], "p") {
+		hour += 12
+	}
+	return fmt.Sprintf("%02d:%s%s", hour, m[2], m[3])
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePubDate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+	}{
+		{"rfc1123z", "Mon, 02 Jan 2006 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"rfc1123 gmt", "Mon, 02 Jan 2006 15:04:05 GMT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"named zone", "Mon, 02 Jan 2006 15:04:05 PST", time.Date(2006, 1, 2, 23, 4, 5, 0, time.UTC)},
+		{"named zone in parentheses", "Mon, 02 Jan 2006 15:04:05 +0100 (CET)", time.Date(2006, 1, 2, 14, 4, 5, 0, time.UTC)},
+		{"single digit day", "Mon, 2 Jan 2006 15:04:05 +0000", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"two digit year", "Mon, 02 Jan 06 15:04 +0000", time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"full weekday and month", "Monday, 2 January 2006 15:04:05 +0000", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"extra whitespace", "  Mon,  02 Jan 2006\n15:04:05 GMT ", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"rfc3339", "2006-01-02T15:04:05Z", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"rfc3339 nano offset", "2006-01-02T15:04:05.123+02:00", time.Date(2006, 1, 2, 13, 4, 5, 123000000, time.UTC)},
+		{"iso without colon in offset", "2006-01-02T15:04:05+0200", time.Date(2006, 1, 2, 13, 4, 5, 0, time.UTC)},
+		{"iso without seconds", "2006-01-02T15:04+02:00", time.Date(2006, 1, 2, 13, 4, 0, 0, time.UTC)},
+		{"iso without zone", "2006-01-02T15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"sql timestamp", "2006-01-02 15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"date only", "2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"ansic", "Mon Jan 2 15:04:05 2006", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"long date", "January 2, 2006", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"gmt hour offset", "Mon, 02 Jan 2006 15:04:05 GMT+2", time.Date(2006, 1, 2, 13, 4, 5, 0, time.UTC)},
+		{"utc offset with minutes", "2006-01-02 15:04:05 UTC-05:30", time.Date(2006, 1, 2, 20, 34, 5, 0, time.UTC)},
+		{"twelve hour clock", "Jan 2, 2006 3:04 PM", time.Date(2006, 1, 2, 15, 4, 0, 0, time.UTC)},
+		{"twelve hour clock with zone", "January 2, 2006 at 3:04 pm EST", time.Date(2006, 1, 2, 20, 4, 0, 0, time.UTC)},
+		{"midnight on twelve hour clock", "2 Jan 2006 12:30:00 a.m. GMT+1", time.Date(2006, 1, 1, 23, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePubDate(tt.value)
+			if err != nil {
+				t.Fatalf("parsePubDate(%q) failed: %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parsePubDate(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePubDateInvalid(t *testing.T) {
+	for _, value := range []string{"", "   ", "yesterday", "2006-13-45", "13:04 PM"} {
+		if got, err := parsePubDate(value); err == nil {
+			t.Errorf("parsePubDate(%q) = %v, want an error", value, got)
+		}
+	}
+}
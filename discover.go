@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/rdf+xml":   true,
+}
+
+var commonFeedPaths = []string{
+	"/feed",
+	"/rss",
+	"/rss.xml",
+	"/atom.xml",
+	"/feed.xml",
+	"/index.xml",
+	"/feed.json",
+}
+
+type feedCandidate struct {
+	URL   string
+	Title string
+}
+
+type page struct {
+	URL         string
+	ContentType string
+	Data        []byte
+}
+
+func resolveFeedURL(ctx context.Context, f Fetcher, rawURL string, header http.Header) (string, error) {
+	p, err := f.FetchPage(ctx, rawURL, header)
+	if err != nil {
+		return "", err
+	}
+	if _, err := parseFeed(p.Data, p.ContentType); err == nil {
+		return rawURL, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(p.ContentType)
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return "", fmt.Errorf("%s is neither a feed nor an HTML page", rawURL)
+	}
+
+	candidates, err := discoverFeeds(ctx, f, p, header)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no feed found at %s", rawURL)
+	case 1:
+		return candidates[0].URL, nil
+	default:
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "multiple feeds found at %s, add one of them instead:", rawURL)
+		for _, c := range candidates {
+			if c.Title != "" {
+				fmt.Fprintf(&sb, "\n* %s (%s)", c.URL, c.Title)
+			} else {
+				fmt.Fprintf(&sb, "\n* %s", c.URL)
+			}
+		}
+		return "", errors.New(sb.String())
+	}
+}
+
+func discoverFeeds(ctx context.Context, f Fetcher, p *page, header http.Header) ([]feedCandidate, error) {
+	base, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := html.Parse(bytes.NewReader(p.Data))
+	if err != nil {
+		return nil, fmt.Errorf("parsing of HTML page failed: %v", err)
+	}
+
+	candidates := feedLinks(doc, base)
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	for _, path := range commonFeedPaths {
+		probeURL := base.ResolveReference(&url.URL{Path: path}).String()
+		probe, err := f.FetchPage(ctx, probeURL, header)
+		if err != nil {
+			continue
+		}
+		feed, err := parseFeed(probe.Data, probe.ContentType)
+		if err != nil {
+			continue
+		}
+		candidates = appendCandidate(candidates, feedCandidate{URL: probeURL, Title: feed.Channel.Title})
+	}
+
+	return candidates, nil
+}
+
+func feedLinks(doc *html.Node, base *url.URL) []feedCandidate {
+	var candidates []feedCandidate
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "base":
+				if href := attr(n, "href"); href != "" {
+					if u, err := base.Parse(href); err == nil {
+						base = u
+					}
+				}
+			case "link":
+				if isFeedLink(n) {
+					if u, err := base.Parse(attr(n, "href")); err == nil {
+						candidates = appendCandidate(candidates, feedCandidate{URL: u.String(), Title: attr(n, "title")})
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	return candidates
+}
+
+func isFeedLink(n *html.Node) bool {
+	if attr(n, "href") == "" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(attr(n, "type"))
+	if !feedLinkTypes[mediaType] {
+		return false
+	}
+	for _, rel := range strings.Fields(strings.ToLower(attr(n, "rel"))) {
+		if rel == "alternate" {
+			return true
+		}
+	}
+	return false
+}
+
+func appendCandidate(candidates []feedCandidate, candidate feedCandidate) []feedCandidate {
+	for _, c := range candidates {
+		if c.URL == candidate.URL {
+			return candidates
+		}
+	}
+	return append(candidates, candidate)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResolveFeedURL(t *testing.T) {
+	html := http.Header{"Content-Type": {"text/html; charset=utf-8"}}
+	rss := http.Header{"Content-Type": {"application/rss+xml"}}
+
+	tests := []struct {
+		name     string
+		fixtures map[string]fixture
+		want     string
+		wantErr  string
+	}{
+		{
+			name: "feed url",
+			fixtures: map[string]fixture{
+				"https://example.com/blog": {Header: rss, File: "rss.xml"},
+			},
+			want: "https://example.com/blog",
+		},
+		{
+			name: "alternate link resolved against base href",
+			fixtures: map[string]fixture{
+				"https://example.com/blog": {Header: html, Body: `<html><head>
+					<base href="https://cdn.example.com/site/">
+					<link rel="stylesheet" href="style.css">
+					<link rel="Alternate" type="application/rss+xml; charset=utf-8" href="feed.xml" title="Posts">
+				</head></html>`},
+			},
+			want: "https://cdn.example.com/site/feed.xml",
+		},
+		{
+			name: "common path probed without links",
+			fixtures: map[string]fixture{
+				"https://example.com/blog":    {Header: html, Body: `<html><head><title>Blog</title></head></html>`},
+				"https://example.com/rss.xml": {Header: rss, File: "rss.xml"},
+			},
+			want: "https://example.com/rss.xml",
+		},
+		{
+			name: "multiple candidates",
+			fixtures: map[string]fixture{
+				"https://example.com/blog": {Header: html, Body: `<html><head>
+					<link rel="alternate" type="application/rss+xml" href="/posts.xml" title="Posts">
+					<link rel="alternate" type="application/atom+xml" href="/comments.xml">
+				</head></html>`},
+			},
+			wantErr: "multiple feeds found at https://example.com/blog, add one of them instead:\n" +
+				"* https://example.com/posts.xml (Posts)\n" +
+				"* https://example.com/comments.xml",
+		},
+		{
+			name: "no feed",
+			fixtures: map[string]fixture{
+				"https://example.com/blog": {Header: html, Body: `<html></html>`},
+			},
+			wantErr: "no feed found at https://example.com/blog",
+		},
+		{
+			name: "neither feed nor page",
+			fixtures: map[string]fixture{
+				"https://example.com/blog": {Header: http.Header{"Content-Type": {"image/png"}}, Body: "png"},
+			},
+			wantErr: "https://example.com/blog is neither a feed nor an HTML page",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher, _ := newFixtureFetcher(tt.fixtures)
+			got, err := resolveFeedURL(context.Background(), fetcher, "https://example.com/blog", nil)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("resolveFeedURL error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveFeedURL failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveFeedURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchPageUsesConfiguredLimit(t *testing.T) {
+	fetcher, _ := newFixtureFetcher(map[string]fixture{
+		"https://example.com/blog": {Body: strings.Repeat("x", 64)},
+	})
+	fetcher.maxPageBytes = 32
+
+	_, err := fetcher.FetchPage(context.Background(), "https://example.com/blog", nil)
+	var tooLarge *feedTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 32 {
+		t.Fatalf("FetchPage error = %v, want the configured limit of 32 bytes", err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type enclosure struct {
+	Kind            string
+	URL             string
+	MimeType        string
+	Length          sql.NullInt64
+	DurationSeconds sql.NullInt32
+}
+
+func itemEnclosures(item RSSItem) []enclosure {
+	var enclosures []enclosure
+	add := func(kind string, e RSSEnclosure, fallbackDuration string) {
+		url := strings.TrimSpace(e.URL)
+		if url == "" {
+			return
+		}
+		for _, existing := range enclosures {
+			if existing.URL == url {
+				return
+			}
+		}
+
+		length := e.Length
+		if length == "" {
+			length = e.FileSize
+		}
+		duration := e.Duration
+		if duration == "" {
+			duration = fallbackDuration
+		}
+
+		encl := enclosure{
+			Kind:     kind,
+			URL:      url,
+			MimeType: strings.TrimSpace(e.Type),
+		}
+		if n, err := strconv.ParseInt(strings.TrimSpace(length), 10, 64); err == nil && n > 0 {
+			encl.Length = sql.NullInt64{Int64: n, Valid: true}
+		}
+		if seconds, ok := parseMediaDuration(duration); ok {
+			encl.DurationSeconds = sql.NullInt32{Int32: seconds, Valid: true}
+		}
+		enclosures = append(enclosures, encl)
+	}
+
+	for _, e := range item.Enclosures {
+		add("enclosure", e, item.ItunesDuration)
+	}
+	for _, e := range item.MediaContent {
+		add("media", e, "")
+	}
+	for _, group := range item.MediaGroups {
+		for _, e := range group.Content {
+			add("media", e, "")
+		}
+	}
+	for _, e := range item.MediaThumbnails {
+		add("thumbnail", e, "")
+	}
+	for _, group := range item.MediaGroups {
+		for _, e := range group.Thumbnails {
+			add("thumbnail", e, "")
+		}
+	}
+
+	return enclosures
+}
+
+// parseMediaDuration accepts the itunes:duration forms "SS", "MM:SS" and
+// "HH:MM:SS", each optionally with fractional seconds.
+func parseMediaDuration(value string) (int32, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+
+	var total float64
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		total = total*60 + n
+	}
+	if total > math.MaxInt32 {
+		return 0, false
+	}
+
+	return int32(math.Round(total)), true
+}
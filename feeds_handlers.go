@@ -2,32 +2,139 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/timpinoy/bd-aggregator/internal/database"
+	"strings"
 	"time"
 )
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
-	if len(cmd.Args) != 2 {
-		return fmt.Errorf("usage: %s <feed_name> <url>", cmd.Name)
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	var credFlags credentialFlags
+	credFlags.register(fs, false)
+	fullArticle := fs.Bool("full-article", false, "download and extract the full article of each post")
+	var scrape scrapeFlags
+	scrape.register(fs)
+	var jsonFlags jsonMappingFlags
+	jsonFlags.register(fs)
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil || len(args) < 1 || len(args) > 2 || (scrape.isSet() && jsonFlags.isSet()) {
+		return fmt.Errorf("usage: %s [feed_name] <url> [--full-article] [--basic|--bearer|--cookie|--header ...] [--scrape-item selector ... | --json-items path ...]", cmd.Name)
+	}
+
+	var creds feedCredentials
+	if err := credFlags.apply(&creds); err != nil {
+		return err
+	}
+
+	rawURL := args[len(args)-1]
+	if isFileURL(rawURL) {
+		if _, err := filePath(rawURL); err != nil {
+			return err
+		}
+	}
+	feedUrl := rawURL
+	kind := feedKindFeed
+	var scrapeRules, jsonMapping sql.NullString
+	if scrape.isSet() {
+		if err := scrape.rules.validate(); err != nil {
+			return err
+		}
+		data, err := json.Marshal(scrape.rules)
+		if err != nil {
+			return err
+		}
+		kind = feedKindScrape
+		scrapeRules = sql.NullString{String: string(data), Valid: true}
+	} else if jsonFlags.isSet() {
+		if err := jsonFlags.mapping.validate(); err != nil {
+			return err
+		}
+		data, err := json.Marshal(jsonFlags.mapping)
+		if err != nil {
+			return err
+		}
+		kind = feedKindJSON
+		jsonMapping = sql.NullString{String: string(data), Valid: true}
+	} else if !isFileURL(rawURL) {
+		feedUrl, err = resolveFeedURL(context.Background(), s.fetcher, rawURL, creds.header())
+		if err != nil {
+			return fmt.Errorf("resolving of feed url failed: %v", err)
+		}
+		// Credentials were given for the host the user typed, so don't send
+		// them to a feed the page pointed at elsewhere.
+		if !creds.isEmpty() && hostOf(feedUrl) != hostOf(rawURL) {
+			return fmt.Errorf("discovered feed %s is on another host, add it explicitly to use these credentials with it", feedUrl)
+		}
+		if feedUrl != rawURL {
+			fmt.Printf("Discovered feed %s\n", feedUrl)
+		}
+	}
+
+	var parsed *RSSFeed
+	feedName := ""
+	if len(args) == 2 {
+		feedName = args[0]
+	}
+	if feedName == "" || kind != feedKindFeed {
+		parse, err := feedParser(database.Feed{Kind: kind, ScrapeRules: scrapeRules, JsonMapping: jsonMapping})
+		if err != nil {
+			return err
+		}
+		result, err := s.fetcher.FetchFeed(context.Background(), fetchRequest{
+			URL:      feedUrl,
+			MaxBytes: s.cfg.MaxFeedBytes,
+			Header:   creds.header(),
+			Parse:    parse,
+		})
+		if err != nil {
+			return fmt.Errorf("fetching of feed failed: %v", err)
+		}
+		parsed = result.Feed
+		if kind != feedKindFeed {
+			fmt.Printf("Matched %d items\n", len(parsed.Channel.Item))
+		}
+	}
+	if feedName == "" {
+		feedName = strings.TrimSpace(parsed.Channel.Title)
+		if feedName == "" {
+			return fmt.Errorf("feed %s has no title, please provide a feed name", feedUrl)
+		}
 	}
-	feedName := cmd.Args[0]
-	feedUrl := cmd.Args[1]
 
 	cfp := database.CreateFeedParams{
-		ID:        uuid.New(),
-		Name:      feedName,
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		Url:       feedUrl,
-		UserID:    user.ID,
+		ID:               uuid.New(),
+		Name:             feedName,
+		CreatedAt:        time.Now().UTC(),
+		UpdatedAt:        time.Now().UTC(),
+		Url:              feedUrl,
+		UserID:           user.ID,
+		FetchFullArticle: *fullArticle,
+		Kind:             kind,
+		ScrapeRules:      scrapeRules,
+		JsonMapping:      jsonMapping,
 	}
 	feed, err := s.db.CreateFeed(context.Background(), cfp)
 	if err != nil {
 		return fmt.Errorf("creation of feed failed: %v", err)
 	}
-	fmt.Printf("Added feed %s\n", cfp)
+	if !creds.isEmpty() {
+		if err := saveFeedCredentials(s, feed.ID, creds); err != nil {
+			if delErr := s.db.DeleteFeed(context.Background(), feed.ID); delErr != nil {
+				return fmt.Errorf("storing of feed credentials failed: %v, removal of feed failed: %v", err, delErr)
+			}
+			return fmt.Errorf("storing of feed credentials failed: %v", err)
+		}
+	}
+	fmt.Printf("Added feed %s\n", feed.Name)
+
+	if parsed != nil {
+		updateFeedMetadata(s, feed, parsed)
+	}
 
 	cffp := database.CreateFeedFollowParams{
 		ID:        uuid.New(),
@@ -45,6 +152,84 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	return nil
 }
 
+func handlerEditFeed(s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	var credFlags credentialFlags
+	credFlags.register(fs, true)
+	fullArticle := fs.Bool("full-article", false, "download and extract the full article of each post")
+	args, err := parseFlags(fs, cmd.Args)
+	fullArticleSet := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "full-article" {
+			fullArticleSet = true
+		}
+	})
+	if err != nil || len(args) != 1 || (!credFlags.isSet() && !fullArticleSet) {
+		return fmt.Errorf("usage: %s <feed_url> [--full-article=true|false] [--basic user:password] [--bearer token] [--cookie cookie] [--header 'Name: value']... [--clear-auth]", cmd.Name)
+	}
+
+	feed, err := s.db.GetFeedByURL(context.Background(), args[0])
+	if err != nil {
+		return fmt.Errorf("retrieval of feed failed: %v", err)
+	}
+	if feed.UserID != user.ID {
+		return fmt.Errorf("feed %s was added by another user", feed.Name)
+	}
+
+	if fullArticleSet {
+		err = s.db.SetFeedFetchFullArticle(context.Background(), database.SetFeedFetchFullArticleParams{
+			ID:               feed.ID,
+			FetchFullArticle: *fullArticle,
+		})
+		if err != nil {
+			return fmt.Errorf("updating of full article mode failed: %v", err)
+		}
+	}
+
+	if credFlags.isSet() {
+		// --clear-auth replaces the stored credentials, so they don't need to
+		// be readable, e.g. after the credentials key was lost.
+		var creds feedCredentials
+		if !credFlags.clearAuth {
+			creds, err = loadFeedCredentials(s, feed.ID)
+			if err != nil {
+				return fmt.Errorf("retrieval of feed credentials failed: %v", err)
+			}
+		}
+		if err := credFlags.apply(&creds); err != nil {
+			return err
+		}
+		if err := saveFeedCredentials(s, feed.ID, creds); err != nil {
+			return fmt.Errorf("storing of feed credentials failed: %v", err)
+		}
+	}
+
+	fmt.Printf("Updated feed %s\n", feed.Name)
+	return nil
+}
+
+func handlerEnableFeed(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <feed_url>", cmd.Name)
+	}
+
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("retrieval of feed failed: %v", err)
+	}
+	if feed.UserID != user.ID {
+		return fmt.Errorf("feed %s was added by another user", feed.Name)
+	}
+
+	err = s.db.EnableFeed(context.Background(), feed.ID)
+	if err != nil {
+		return fmt.Errorf("enabling of feed failed: %v", err)
+	}
+
+	fmt.Printf("Enabled feed %s\n", feed.Name)
+	return nil
+}
+
 func handlerFeeds(s *state, cmd command) error {
 	feeds, err := s.db.GetFeeds(context.Background())
 	if err != nil {
@@ -74,6 +259,43 @@ func printFeed(feed database.Feed, username string) {
 	fmt.Printf("* Updated:       %v\n", feed.UpdatedAt)
 	fmt.Printf("* Name:          %s\n", feed.Name)
 	fmt.Printf("* URL:           %s\n", feed.Url)
+	if feed.Kind != feedKindFeed {
+		fmt.Printf("* Kind:          %s\n", feed.Kind)
+	}
+	if feed.ScrapeRules.Valid {
+		fmt.Printf("* ScrapeRules:   %s\n", feed.ScrapeRules.String)
+	}
+	if feed.JsonMapping.Valid {
+		fmt.Printf("* JSONMapping:   %s\n", feed.JsonMapping.String)
+	}
+	if feed.Title != "" {
+		fmt.Printf("* Title:         %s\n", feed.Title)
+	}
+	if feed.Description != "" {
+		fmt.Printf("* Description:   %s\n", feed.Description)
+	}
+	if feed.SiteUrl != "" {
+		fmt.Printf("* Site:          %s\n", feed.SiteUrl)
+	}
+	if feed.ImageUrl != "" {
+		fmt.Printf("* Image:         %s\n", feed.ImageUrl)
+	}
+	if feed.Language != "" {
+		fmt.Printf("* Language:      %s\n", feed.Language)
+	}
 	fmt.Printf("* User:          %s\n", username)
 	fmt.Printf("* LastFetchedAt: %v\n", feed.LastFetchedAt.Time)
+	if feed.FetchFullArticle {
+		fmt.Printf("* FullArticle:   %v\n", feed.FetchFullArticle)
+	}
+	if feed.GoneAt.Valid {
+		fmt.Printf("* GoneAt:        %v\n", feed.GoneAt.Time)
+	}
+	if feed.DisabledAt.Valid {
+		fmt.Printf("* DisabledAt:    %v\n", feed.DisabledAt.Time)
+	}
+	if feed.ConsecutiveFailures > 0 {
+		fmt.Printf("* Failures:      %d\n", feed.ConsecutiveFailures)
+		fmt.Printf("* LastError:     %s\n", feed.LastError.String)
+	}
 }
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/timpinoy/bd-aggregator/internal/database"
+)
+
+type Fetcher interface {
+	FetchFeed(ctx context.Context, fr fetchRequest) (*fetchResult, error)
+	FetchPage(ctx context.Context, rawURL string, header http.Header) (*page, error)
+	PostForm(ctx context.Context, rawURL string, form url.Values) error
+}
+
+func feedParser(feed database.Feed) (func(p *page) (*RSSFeed, error), error) {
+	switch feed.Kind {
+	case feedKindScrape:
+		var rules scrapeRules
+		if err := json.Unmarshal([]byte(feed.ScrapeRules.String), &rules); err != nil {
+			return nil, fmt.Errorf("invalid scrape rules: %v", err)
+		}
+		return rules.parse, nil
+	case feedKindJSON:
+		var mapping jsonMapping
+		if err := json.Unmarshal([]byte(feed.JsonMapping.String), &mapping); err != nil {
+			return nil, fmt.Errorf("invalid json mapping: %v", err)
+		}
+		return mapping.parse, nil
+	default:
+		return parseFeedPage, nil
+	}
+}
+
+type httpFetcher struct {
+	client       *http.Client
+	maxPageBytes int64
+}
+
+func newHTTPFetcher(client *http.Client, maxPageBytes int64) *httpFetcher {
+	if client == nil {
+		client = &http.Client{
+			Timeout: time.Second * 10,
+		}
+	}
+	return &httpFetcher{client: client, maxPageBytes: maxPageBytes}
+}
+
+func (f *httpFetcher) FetchFeed(ctx context.Context, fr fetchRequest) (*fetchResult, error) {
+	// Only a feed added with a file:// url is read from disk, never a url a
+	// server redirected to or a link found in a feed.
+	if isFileURL(fr.URL) {
+		return fetchFile(fr)
+	}
+
+	permanentURL := ""
+	permanent := true
+	httpClient := *f.client
+	checkRedirect := redirectPolicy(fr.Header)
+	httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := checkRedirect(req, via); err != nil {
+			return err
+		}
+		switch req.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			if permanent {
+				permanentURL = req.URL.String()
+			}
+		default:
+			permanent = false
+		}
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fr.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	for name, values := range fr.Header {
+		req.Header[name] = values
+	}
+	if fr.ETag != "" {
+		req.Header.Set("If-None-Match", fr.ETag)
+	}
+	if fr.LastModified != "" {
+		req.Header.Set("If-Modified-Since", fr.LastModified)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &fetchResult{
+		StatusCode:   resp.StatusCode,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		PermanentURL: permanentURL,
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		result.ETag = fr.ETag
+		result.LastModified = fr.LastModified
+		return result, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		statusErr := &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				statusErr.Throttled = true
+				statusErr.RetryAfter = retryAfter
+			} else if resp.StatusCode == http.StatusTooManyRequests {
+				statusErr.Throttled = true
+				statusErr.RetryAfter = defaultRetryAfter
+			}
+		}
+		return nil, statusErr
+	}
+
+	data, err := readBody(resp, fr.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := fr.parse(&page{
+		URL:         resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+		Data:        data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Feed = feed
+	result.Hub, result.Topic = webSubLinks(resp.Header, feed)
+	if result.Hub != "" && result.Topic == "" {
+		result.Topic = resp.Request.URL.String()
+	}
+
+	return result, nil
+}
+
+func (fr fetchRequest) parse(p *page) (*RSSFeed, error) {
+	parse := fr.Parse
+	if parse == nil {
+		parse = parseFeedPage
+	}
+	return parse(p)
+}
+
+func fetchFile(fr fetchRequest) (*fetchResult, error) {
+	path, err := filePath(fr.URL)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &fetchResult{
+		StatusCode:   http.StatusOK,
+		LastModified: info.ModTime().UTC().Format(http.TimeFormat),
+	}
+	if result.LastModified == fr.LastModified {
+		result.StatusCode = http.StatusNotModified
+		result.NotModified = true
+		return result, nil
+	}
+
+	data, err := readEncodedBody(http.Header{}, info.Size(), f, fr.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	result.Feed, err = fr.parse(&page{
+		URL:         fr.URL,
+		ContentType: mime.TypeByExtension(filepath.Ext(path)),
+		Data:        data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (f *httpFetcher) FetchPage(ctx context.Context, rawURL string, header http.Header) (*page, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	httpClient := *f.client
+	httpClient.CheckRedirect = redirectPolicy(header)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	data, err := readBody(resp, f.maxPageBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &page{
+		URL:         resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+		Data:        data,
+	}, nil
+}
+
+func (f *httpFetcher) PostForm(ctx context.Context, rawURL string, form url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", rawURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "gator")
+
+	httpClient := *f.client
+	httpClient.CheckRedirect = redirectPolicy(nil)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	return nil
+}
+
+// redirectPolicy drops the feed's credentials and custom headers when a
+// redirect leaves the host they were configured for. Go itself only strips
+// Authorization and Cookie, and only when leaving the domain.
+func redirectPolicy(credentials http.Header) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if !isHTTPURL(req.URL.String()) {
+			return fmt.Errorf("redirect to unsupported url %s", req.URL)
+		}
+		if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+			for name := range credentials {
+				req.Header.Del(name)
+			}
+			req.Header.Del("Authorization")
+			req.Header.Del("Cookie")
+		}
+		return nil
+	}
+}
+
+func isHTTPURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+func isFileURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Scheme == "file"
+}
+
+// filePath returns the local path of a file:// url. Urls naming another
+// host are refused, as opening them would reach out to a network share.
+func filePath(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Host != "" && !strings.EqualFold(u.Host, "localhost") {
+		return "", fmt.Errorf("file url %s names a remote host", rawURL)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type fixture struct {
+	StatusCode int
+	Header     http.Header
+	File       string
+	Body       string
+}
+
+type fixtureTransport struct {
+	fixtures map[string]fixture
+	requests []*http.Request
+}
+
+func newFixtureFetcher(fixtures map[string]fixture) (*httpFetcher, *fixtureTransport) {
+	transport := &fixtureTransport{fixtures: fixtures}
+	return newHTTPFetcher(&http.Client{Transport: transport}, 0), transport
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, req)
+
+	f, ok := t.fixtures[req.URL.String()]
+	if !ok {
+		f = fixture{StatusCode: http.StatusNotFound}
+	}
+	if f.StatusCode == 0 {
+		f.StatusCode = http.StatusOK
+	}
+
+	body := f.Body
+	if f.File != "" {
+		data, err := os.ReadFile(filepath.Join("testdata", f.File))
+		if err != nil {
+			return nil, err
+		}
+		body = string(data)
+	}
+
+	header := f.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		StatusCode: f.StatusCode,
+		Status:     http.StatusText(f.StatusCode),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestFixtureFetcherFetchFeed(t *testing.T) {
+	fetcher, transport := newFixtureFetcher(map[string]fixture{
+		"https://example.com/feed.xml": {
+			Header: http.Header{"Content-Type": {"application/rss+xml"}, "Etag": {`"v1"`}},
+			File:   "rss.xml",
+		},
+	})
+
+	result, err := fetcher.FetchFeed(context.Background(), fetchRequest{URL: "https://example.com/feed.xml"})
+	if err != nil {
+		t.Fatalf("FetchFeed failed: %v", err)
+	}
+	if result.ETag != `"v1"` {
+		t.Errorf("ETag = %q, want %q", result.ETag, `"v1"`)
+	}
+	if got := len(result.Feed.Channel.Item); got != 2 {
+		t.Fatalf("got %d items, want 2", got)
+	}
+	if got := result.Feed.Channel.Item[0].Title; got != "First & foremost" {
+		t.Errorf("title = %q, want %q", got, "First & foremost")
+	}
+	if got := transport.requests[0].Header.Get("User-Agent"); got != "gator" {
+		t.Errorf("User-Agent = %q, want %q", got, "gator")
+	}
+}
+
+func TestFixtureFetcherMissingFixture(t *testing.T) {
+	fetcher, _ := newFixtureFetcher(nil)
+
+	_, err := fetcher.FetchFeed(context.Background(), fetchRequest{URL: "https://example.com/missing.xml"})
+	statusErr, ok := err.(*httpStatusError)
+	if !ok {
+		t.Fatalf("err = %v, want *httpStatusError", err)
+	}
+	if statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", statusErr.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawURL  string
+		want    string
+		wantErr bool
+	}{
+		{"empty host", "file:///var/feeds/feed.xml", filepath.FromSlash("/var/feeds/feed.xml"), false},
+		{"localhost", "file://localhost/var/feeds/feed.xml", filepath.FromSlash("/var/feeds/feed.xml"), false},
+		{"localhost in capitals", "file://LOCALHOST/var/feeds/feed.xml", filepath.FromSlash("/var/feeds/feed.xml"), false},
+		{"remote host", "file://fileserver/share/feed.xml", "", true},
+		{"localhost with port", "file://localhost:8080/feed.xml", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filePath(tt.rawURL)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("filePath(%q) = %q, want an error", tt.rawURL, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filePath(%q) failed: %v", tt.rawURL, err)
+			}
+			if got != tt.want {
+				t.Errorf("filePath(%q) = %q, want %q", tt.rawURL, got, tt.want)
+			}
+		})
+	}
+}
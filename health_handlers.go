@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/timpinoy/bd-aggregator/internal/database"
+)
+
+var feedHealthSorts = map[string]func(a, b database.GetFeedHealthRow) bool{
+	"name": func(a, b database.GetFeedHealthRow) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	},
+	"failures": func(a, b database.GetFeedHealthRow) bool {
+		return a.ConsecutiveFailures > b.ConsecutiveFailures
+	},
+	"latency": func(a, b database.GetFeedHealthRow) bool {
+		return a.AvgDurationMs.Float64 > b.AvgDurationMs.Float64
+	},
+	"success": func(a, b database.GetFeedHealthRow) bool {
+		return a.LastSuccessAt.Time.Before(b.LastSuccessAt.Time)
+	},
+	"items": func(a, b database.GetFeedHealthRow) bool {
+		return a.AvgItemCount.Float64 > b.AvgItemCount.Float64
+	},
+}
+
+func handlerFeedHealth(s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	unhealthy := fs.Bool("unhealthy", false, "only list feeds that are failing, disabled or gone")
+	sortBy := fs.String("sort", "name", "sort by name, failures, latency, success or items")
+	args, err := parseFlags(fs, cmd.Args)
+	less, ok := feedHealthSorts[*sortBy]
+	if err != nil || len(args) != 0 || !ok {
+		return fmt.Errorf("usage: %s [--unhealthy] [--sort name|failures|latency|success|items]", cmd.Name)
+	}
+
+	rows, err := s.db.GetFeedHealth(context.Background())
+	if err != nil {
+		return fmt.Errorf("retrieval of feed health failed: %v", err)
+	}
+
+	var feeds []database.GetFeedHealthRow
+	for _, row := range rows {
+		if status := feedStatus(row); *unhealthy && (status == "ok" || status == "pending") {
+			continue
+		}
+		feeds = append(feeds, row)
+	}
+	sort.SliceStable(feeds, func(i, j int) bool {
+		return less(feeds[i], feeds[j])
+	})
+
+	if len(feeds) == 0 {
+		fmt.Println("No feeds to report.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tHTTP\tLAST SUCCESS\tFAILURES\tAVG TIME\tITEMS/FETCH\tLAST ERROR")
+	for _, feed := range feeds {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			feed.Name,
+			feedStatus(feed),
+			formatStatusCode(feed),
+			formatLastSuccess(feed),
+			feed.ConsecutiveFailures,
+			formatAvgDuration(feed),
+			formatAvgItems(feed),
+			truncate(feed.LastError.String, 60),
+		)
+	}
+	return w.Flush()
+}
+
+func feedStatus(feed database.GetFeedHealthRow) string {
+	switch {
+	case feed.GoneAt.Valid:
+		return "gone"
+	case feed.DisabledAt.Valid:
+		return "disabled"
+	case feed.ConsecutiveFailures > 0:
+		return "failing"
+	case !feed.FetchCount.Valid:
+		return "pending"
+	default:
+		return "ok"
+	}
+}
+
+func formatStatusCode(feed database.GetFeedHealthRow) string {
+	if !feed.LastStatusCode.Valid {
+		return "-"
+	}
+	return fmt.Sprint(feed.LastStatusCode.Int32)
+}
+
+func formatLastSuccess(feed database.GetFeedHealthRow) string {
+	if !feed.LastSuccessAt.Valid {
+		return "never"
+	}
+	return feed.LastSuccessAt.Time.Format(time.DateTime)
+}
+
+func formatAvgDuration(feed database.GetFeedHealthRow) string {
+	if !feed.AvgDurationMs.Valid {
+		return "-"
+	}
+	return (time.Duration(feed.AvgDurationMs.Float64) * time.Millisecond).String()
+}
+
+func formatAvgItems(feed database.GetFeedHealthRow) string {
+	if !feed.AvgItemCount.Valid {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", feed.AvgItemCount.Float64)
+}
+
+func truncate(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len([]rune(s)) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit-1]) + "…"
+}
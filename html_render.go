@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/term"
+)
+
+const defaultTerminalWidth = 80
+
+type textBlock struct {
+	prefix      string
+	firstPrefix string
+	text        string
+	pre         bool
+	tight       bool
+}
+
+type listContext struct {
+	ordered bool
+	counter int
+}
+
+type htmlRenderer struct {
+	blocks []textBlock
+	inline strings.Builder
+	quotes int
+	lists  []listContext
+	bullet string
+	inPre  bool
+	links  []string
+}
+
+func terminalWidth() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultTerminalWidth
+}
+
+func renderHTML(content string, width int) string {
+	nodes, err := html.ParseFragment(strings.NewReader(content), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return content
+	}
+
+	r := &htmlRenderer{}
+	for _, n := range nodes {
+		r.walk(n)
+	}
+	r.flush()
+
+	return r.render(width)
+}
+
+func (r *htmlRenderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		r.children(n)
+		return
+	}
+
+	switch n.Data {
+	case "script", "style", "head", "noscript", "template", "iframe":
+	case "br":
+		r.inline.WriteString("\n")
+	case "p", "div", "section", "article", "header", "footer", "figure", "figcaption", "table", "tr", "dl", "dt", "dd", "main", "aside", "nav":
+		r.flush()
+		r.children(n)
+		r.flush()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.flush()
+		r.children(n)
+		r.flushHeading(n.Data == "h1")
+	case "ul", "ol":
+		r.flush()
+		r.lists = append(r.lists, listContext{ordered: n.Data == "ol"})
+		r.children(n)
+		r.flush()
+		r.lists = r.lists[:len(r.lists)-1]
+	case "li":
+		r.flush()
+		if len(r.lists) > 0 {
+			list := &r.lists[len(r.lists)-1]
+			list.counter++
+			if list.ordered {
+				r.bullet = fmt.Sprintf("%d. ", list.counter)
+			} else {
+				r.bullet = "• "
+			}
+		}
+		r.children(n)
+		r.flush()
+	case "blockquote":
+		r.flush()
+		r.quotes++
+		r.children(n)
+		r.flush()
+		r.quotes--
+	case "pre":
+		r.flush()
+		r.inPre = true
+		r.children(n)
+		r.inPre = false
+		r.flushPre()
+	case "hr":
+		r.flush()
+		r.blocks = append(r.blocks, textBlock{text: "----"})
+	case "code", "kbd", "samp", "tt":
+		if r.inPre {
+			r.children(n)
+			return
+		}
+		r.inline.WriteString("`")
+		r.children(n)
+		r.inline.WriteString("`")
+	case "em", "i", "cite":
+		r.inline.WriteString("_")
+		r.children(n)
+		r.inline.WriteString("_")
+	case "strong", "b":
+		r.inline.WriteString("*")
+		r.children(n)
+		r.inline.WriteString("*")
+	case "a":
+		r.children(n)
+		href := attr(n, "href")
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return
+		}
+		r.links = append(r.links, href)
+		fmt.Fprintf(&r.inline, "[%d]", len(r.links))
+	case "img":
+		if alt := attr(n, "alt"); alt != "" {
+			fmt.Fprintf(&r.inline, "[image: %s]", alt)
+		} else {
+			r.inline.WriteString("[image]")
+		}
+	case "td", "th":
+		r.children(n)
+		r.inline.WriteString(" ")
+	default:
+		r.children(n)
+	}
+}
+
+func (r *htmlRenderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.walk(c)
+	}
+}
+
+func (r *htmlRenderer) text(data string) {
+	if r.inPre {
+		r.inline.WriteString(data)
+		return
+	}
+	collapsed := strings.Join(strings.Fields(data), " ")
+	if collapsed == "" {
+		if data != "" {
+			r.inline.WriteString(" ")
+		}
+		return
+	}
+	if data[0] == ' ' || data[0] == '\n' || data[0] == '\t' {
+		collapsed = " " + collapsed
+	}
+	if last := data[len(data)-1]; last == ' ' || last == '\n' || last == '\t' {
+		collapsed += " "
+	}
+	r.inline.WriteString(collapsed)
+}
+
+func (r *htmlRenderer) prefixes() (string, string) {
+	prefix := strings.Repeat("> ", r.quotes)
+	for range r.lists {
+		prefix += "  "
+	}
+	if len(r.lists) == 0 || r.bullet == "" {
+		return prefix, prefix
+	}
+
+	base := prefix[:len(prefix)-2]
+	first := base + r.bullet
+	return first, base + strings.Repeat(" ", utf8.RuneCountInString(r.bullet))
+}
+
+func (r *htmlRenderer) flush() {
+	text := strings.TrimSpace(r.inline.String())
+	r.inline.Reset()
+	if text == "" {
+		return
+	}
+
+	first, rest := r.prefixes()
+	r.blocks = append(r.blocks, textBlock{
+		firstPrefix: first,
+		prefix:      rest,
+		text:        text,
+		tight:       len(r.lists) > 0,
+	})
+	r.bullet = ""
+}
+
+func (r *htmlRenderer) flushHeading(major bool) {
+	text := strings.TrimSpace(r.inline.String())
+	if text == "" {
+		r.inline.Reset()
+		return
+	}
+	underline := "-"
+	if major {
+		underline = "="
+	}
+	r.inline.WriteString("\n" + strings.Repeat(underline, utf8.RuneCountInString(text)))
+	r.flush()
+}
+
+func (r *htmlRenderer) flushPre() {
+	text := strings.Trim(r.inline.String(), "\n")
+	r.inline.Reset()
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	prefix, _ := r.prefixes()
+	r.blocks = append(r.blocks, textBlock{
+		firstPrefix: prefix + "    ",
+		prefix:      prefix + "    ",
+		text:        text,
+		pre:         true,
+	})
+}
+
+func (r *htmlRenderer) render(width int) string {
+	var sb strings.Builder
+	for i, block := range r.blocks {
+		if i > 0 {
+			if block.tight && r.blocks[i-1].tight {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(block.wrap(width))
+	}
+
+	if len(r.links) > 0 {
+		sb.WriteString("\n")
+		for i, link := range r.links {
+			fmt.Fprintf(&sb, "\n[%d] %s", i+1, link)
+		}
+	}
+
+	return sb.String()
+}
+
+func (b textBlock) wrap(width int) string {
+	var lines []string
+	prefix := b.firstPrefix
+	for _, hardLine := range strings.Split(b.text, "\n") {
+		if b.pre {
+			lines = append(lines, prefix+hardLine)
+			prefix = b.prefix
+			continue
+		}
+
+		line := prefix
+		lineLen := utf8.RuneCountInString(prefix)
+		empty := true
+		for _, word := range strings.Fields(hardLine) {
+			wordLen := utf8.RuneCountInString(word)
+			if !empty && lineLen+1+wordLen > width {
+				lines = append(lines, line)
+				prefix = b.prefix
+				line = prefix
+				lineLen = utf8.RuneCountInString(prefix)
+				empty = true
+			}
+			if !empty {
+				line += " "
+				lineLen++
+			}
+			line += word
+			lineLen += wordLen
+			empty = false
+		}
+		lines = append(lines, line)
+		prefix = b.prefix
+	}
+	return strings.Join(lines, "\n")
+}
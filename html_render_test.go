@@ -0,0 +1,117 @@
+package main
+
+import "testing"
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		width   int
+		want    string
+	}{
+		{
+			name:    "plain text with entities",
+			content: "Tom &amp; Jerry&#39;s &lt;show&gt;",
+			width:   80,
+			want:    "Tom & Jerry's <show>",
+		},
+		{
+			name:    "paragraphs collapse whitespace",
+			content: "<p>First   paragraph.</p><p>Second\nparagraph.</p>",
+			width:   80,
+			want:    "First paragraph.\n\nSecond paragraph.",
+		},
+		{
+			name:    "wrapping",
+			content: "<p>The quick brown fox jumps over the lazy dog</p>",
+			width:   16,
+			want:    "The quick brown\nfox jumps over\nthe lazy dog",
+		},
+		{
+			name:    "words longer than the width",
+			content: "Supercalifragilisticexpialidocious word",
+			width:   10,
+			want:    "Supercalifragilisticexpialidocious\nword",
+		},
+		{
+			name:    "line breaks",
+			content: "Line one<br>Line two",
+			width:   80,
+			want:    "Line one\nLine two",
+		},
+		{
+			name:    "links become footnotes",
+			content: `<p>Read <a href="https://example.com/a">this</a> and <a href="#top">that</a> or <a href="javascript:void(0)">run</a>.</p>`,
+			width:   80,
+			want:    "Read this[1] and that or run.\n\n[1] https://example.com/a",
+		},
+		{
+			name:    "unordered list",
+			content: "<ul><li>One</li><li>Two</li></ul>",
+			width:   80,
+			want:    "• One\n• Two",
+		},
+		{
+			name:    "nested ordered list",
+			content: "<ol><li>One</li><li>Two<ol><li>Nested</li></ol></li></ol>",
+			width:   80,
+			want:    "1. One\n2. Two\n  1. Nested",
+		},
+		{
+			name:    "wrapped list item",
+			content: "<ul><li>A list item long enough to wrap</li></ul>",
+			width:   16,
+			want:    "• A list item\n  long enough to\n  wrap",
+		},
+		{
+			name:    "blockquote",
+			content: "<blockquote><p>Quoted text that wraps around</p></blockquote>",
+			width:   16,
+			want:    "> Quoted text\n> that wraps\n> around",
+		},
+		{
+			name:    "preformatted code",
+			content: "<pre><code>if x {\n    return\n}</code></pre>",
+			width:   80,
+			want:    "    if x {\n        return\n    }",
+		},
+		{
+			name:    "headings",
+			content: "<h1>Title</h1><h2>Sub</h2><p>Body</p>",
+			width:   80,
+			want:    "Title\n=====\n\nSub\n---\n\nBody",
+		},
+		{
+			name:    "inline markup",
+			content: "<p>Use <code>go test</code>, <em>really</em>, <strong>now</strong>.</p>",
+			width:   80,
+			want:    "Use `go test`, _really_, *now*.",
+		},
+		{
+			name:    "images",
+			content: `<p>Look <img src="a.png" alt="a cat"> <img src="b.png"></p>`,
+			width:   80,
+			want:    "Look [image: a cat] [image]",
+		},
+		{
+			name:    "scripts and styles are dropped",
+			content: "<script>alert(1)</script><style>p{}</style><p>Visible</p>",
+			width:   80,
+			want:    "Visible",
+		},
+		{
+			name:    "horizontal rule",
+			content: "<p>Before</p><hr><p>After</p>",
+			width:   80,
+			want:    "Before\n\n----\n\nAfter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderHTML(tt.content, tt.width); got != tt.want {
+				t.Errorf("renderHTML = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/timpinoy/bd-aggregator/internal/database"
+)
+
+func handlerIngest(s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <feed_name> < feed.xml", cmd.Name)
+	}
+
+	named, err := s.db.GetFeedsByName(context.Background(), cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("retrieval of feed failed: %v", err)
+	}
+	var feeds []database.Feed
+	for _, feed := range named {
+		if feed.UserID == user.ID {
+			feeds = append(feeds, feed)
+		}
+	}
+	switch {
+	case len(named) > 0 && len(feeds) == 0:
+		return fmt.Errorf("feed %s was added by another user", cmd.Args[0])
+	case len(feeds) == 0:
+		return fmt.Errorf("no feed named %s", cmd.Args[0])
+	case len(feeds) > 1:
+		var urls []string
+		for _, feed := range feeds {
+			urls = append(urls, "* "+feed.Url)
+		}
+		return fmt.Errorf("%d feeds are named %s:\n%s", len(feeds), cmd.Args[0], strings.Join(urls, "\n"))
+	}
+
+	items, postsSaved, err := ingestFeed(s, feeds[0], os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Ingested %d items into %s, %d new posts saved\n", items, feeds[0].Name, postsSaved)
+	return nil
+}
+
+func ingestFeed(s *state, feed database.Feed, r io.Reader) (int, int, error) {
+	data, err := readEncodedBody(http.Header{}, -1, r, s.cfg.MaxFeedBytes)
+	if err != nil {
+		return 0, 0, fmt.Errorf("reading of feed failed: %v", err)
+	}
+
+	parse, err := feedParser(feed)
+	if err != nil {
+		return 0, 0, err
+	}
+	parsed, err := parse(&page{URL: feed.Url, Data: data})
+	if err != nil {
+		return 0, 0, fmt.Errorf("parsing of feed failed: %v", err)
+	}
+
+	updateFeedMetadata(s, feed, parsed)
+	return len(parsed.Channel.Item), savePosts(s, feed, parsed), nil
+}
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/timpinoy/bd-aggregator/internal/secrets"
 )
 
 const configFileName = ".gatorconfig.json"
@@ -12,6 +16,12 @@ const configFileName = ".gatorconfig.json"
 type Config struct {
 	DBUrl           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+	MaxFeedBytes    int64  `json:"max_feed_bytes,omitempty"`
+	CredentialsKey  string `json:"credentials_key,omitempty"`
+	HostMinInterval string `json:"host_min_interval,omitempty"`
+	MaxFeedFailures int32  `json:"max_feed_failures,omitempty"`
+	WebSubCallback  string `json:"websub_callback_url,omitempty"`
+	WebSubListen    string `json:"websub_listen_addr,omitempty"`
 }
 
 func (cfg *Config) SetUser(userName string) error {
@@ -19,6 +29,39 @@ func (cfg *Config) SetUser(userName string) error {
 	return write(cfg)
 }
 
+func (cfg *Config) HostInterval() (time.Duration, error) {
+	if cfg.HostMinInterval == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(cfg.HostMinInterval)
+}
+
+// DecryptionKey returns the stored key without creating one, as data
+// encrypted under a lost key can't be recovered by generating a new one.
+func (cfg *Config) DecryptionKey() ([]byte, error) {
+	if cfg.CredentialsKey == "" {
+		return nil, errors.New("credentials_key is missing from the config file")
+	}
+	return base64.StdEncoding.DecodeString(cfg.CredentialsKey)
+}
+
+func (cfg *Config) EncryptionKey() ([]byte, error) {
+	if cfg.CredentialsKey != "" {
+		return base64.StdEncoding.DecodeString(cfg.CredentialsKey)
+	}
+
+	key, err := secrets.NewKey()
+	if err != nil {
+		return nil, err
+	}
+	cfg.CredentialsKey = base64.StdEncoding.EncodeToString(key)
+	if err := write(cfg); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
 func Read() (Config, error) {
 	var cfg Config
 	filePath, err := getConfigFilePath()
@@ -62,10 +105,12 @@ func write(cfg *Config) error {
 		return err
 	}
 
-	err = os.WriteFile(filePath, data, 0644)
+	err = os.WriteFile(filePath, data, 0600)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// WriteFile keeps the mode of an existing file, which may predate the
+	// credentials key.
+	return os.Chmod(filePath, 0600)
 }
@@ -0,0 +1,89 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: enclosures.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createEnclosure = `-- name: CreateEnclosure :exec
+INSERT INTO enclosures(id, created_at, updated_at, post_id, kind, url, mime_type, length, duration_seconds)
+VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8, $9
+       )
+ON CONFLICT (post_id, url) DO NOTHING
+`
+
+type CreateEnclosureParams struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	PostID          uuid.UUID
+	Kind            string
+	Url             string
+	MimeType        string
+	Length          sql.NullInt64
+	DurationSeconds sql.NullInt32
+}
+
+func (q *Queries) CreateEnclosure(ctx context.Context, arg CreateEnclosureParams) error {
+	_, err := q.db.ExecContext(ctx, createEnclosure,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.PostID,
+		arg.Kind,
+		arg.Url,
+		arg.MimeType,
+		arg.Length,
+		arg.DurationSeconds,
+	)
+	return err
+}
+
+const getEnclosuresForPost = `-- name: GetEnclosuresForPost :many
+SELECT id, created_at, updated_at, post_id, kind, url, mime_type, length, duration_seconds
+  FROM enclosures
+ WHERE post_id = $1
+ ORDER BY created_at
+`
+
+func (q *Queries) GetEnclosuresForPost(ctx context.Context, postID uuid.UUID) ([]Enclosure, error) {
+	rows, err := q.db.QueryContext(ctx, getEnclosuresForPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Enclosure
+	for rows.Next() {
+		var i Enclosure
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.PostID,
+			&i.Kind,
+			&i.Url,
+			&i.MimeType,
+			&i.Length,
+			&i.DurationSeconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
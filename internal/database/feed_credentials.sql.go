@@ -0,0 +1,71 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: feed_credentials.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteFeedCredentials = `-- name: DeleteFeedCredentials :exec
+DELETE FROM feed_credentials
+ WHERE feed_id = $1
+`
+
+func (q *Queries) DeleteFeedCredentials(ctx context.Context, feedID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedCredentials, feedID)
+	return err
+}
+
+const getFeedCredentials = `-- name: GetFeedCredentials :one
+SELECT id, created_at, updated_at, feed_id, secret
+  FROM feed_credentials
+ WHERE feed_id = $1
+`
+
+func (q *Queries) GetFeedCredentials(ctx context.Context, feedID uuid.UUID) (FeedCredential, error) {
+	row := q.db.QueryRowContext(ctx, getFeedCredentials, feedID)
+	var i FeedCredential
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.Secret,
+	)
+	return i, err
+}
+
+const upsertFeedCredentials = `-- name: UpsertFeedCredentials :exec
+INSERT INTO feed_credentials(id, created_at, updated_at, feed_id, secret)
+VALUES (
+        $1, $2, $3, $4, $5
+       )
+ON CONFLICT (feed_id) DO UPDATE
+   SET updated_at = EXCLUDED.updated_at,
+       secret = EXCLUDED.secret
+`
+
+type UpsertFeedCredentialsParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	FeedID    uuid.UUID
+	Secret    string
+}
+
+func (q *Queries) UpsertFeedCredentials(ctx context.Context, arg UpsertFeedCredentialsParams) error {
+	_, err := q.db.ExecContext(ctx, upsertFeedCredentials,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FeedID,
+		arg.Secret,
+	)
+	return err
+}
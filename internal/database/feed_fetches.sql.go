@@ -0,0 +1,173 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: feed_fetches.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createFeedFetch = `-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches(id, created_at, updated_at, feed_id, status_code, duration_ms, item_count, succeeded, error)
+VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8, $9
+       )
+`
+
+type CreateFeedFetchParams struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FeedID     uuid.UUID
+	StatusCode sql.NullInt32
+	DurationMs int32
+	ItemCount  int32
+	Succeeded  bool
+	Error      sql.NullString
+}
+
+func (q *Queries) CreateFeedFetch(ctx context.Context, arg CreateFeedFetchParams) error {
+	_, err := q.db.ExecContext(ctx, createFeedFetch,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FeedID,
+		arg.StatusCode,
+		arg.DurationMs,
+		arg.ItemCount,
+		arg.Succeeded,
+		arg.Error,
+	)
+	return err
+}
+
+const deleteFeedFetchesBefore = `-- name: DeleteFeedFetchesBefore :exec
+DELETE FROM feed_fetches
+ WHERE created_at < $1
+`
+
+func (q *Queries) DeleteFeedFetchesBefore(ctx context.Context, createdAt time.Time) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedFetchesBefore, createdAt)
+	return err
+}
+
+const getFeedHealth = `-- name: GetFeedHealth :many
+WITH stats AS (
+    SELECT feed_id,
+           (MAX(created_at) FILTER (WHERE succeeded))::timestamp AS last_success_at,
+           AVG(duration_ms)::float8 AS avg_duration_ms,
+           COALESCE(AVG(item_count) FILTER (WHERE status_code = 200), 0)::float8 AS avg_item_count,
+           COUNT(*) AS fetch_count
+      FROM feed_fetches
+     GROUP BY feed_id
+), latest AS (
+    SELECT DISTINCT ON (feed_id)
+           feed_id,
+           status_code
+      FROM feed_fetches
+     ORDER BY feed_id, created_at DESC
+)
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.etag, feeds.last_modified, feeds.gone_at, feeds.next_fetch_at, feeds.consecutive_failures, feeds.last_error, feeds.disabled_at, feeds.fetch_full_article, feeds.title, feeds.description, feeds.site_url, feeds.image_url, feeds.language, feeds.kind, feeds.scrape_rules, feeds.json_mapping, feeds.update_hints,
+       stats.last_success_at,
+       stats.avg_duration_ms,
+       stats.avg_item_count,
+       stats.fetch_count,
+       latest.status_code AS last_status_code
+  FROM feeds
+  LEFT JOIN stats
+    ON stats.feed_id = feeds.id
+  LEFT JOIN latest
+    ON latest.feed_id = feeds.id
+ ORDER BY feeds.name
+`
+
+type GetFeedHealthRow struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	GoneAt              sql.NullTime
+	NextFetchAt         sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	DisabledAt          sql.NullTime
+	FetchFullArticle    bool
+	Title               string
+	Description         string
+	SiteUrl             string
+	ImageUrl            string
+	Language            string
+	Kind                string
+	ScrapeRules         sql.NullString
+	JsonMapping         sql.NullString
+	UpdateHints         sql.NullString
+	LastSuccessAt       sql.NullTime
+	AvgDurationMs       sql.NullFloat64
+	AvgItemCount        sql.NullFloat64
+	FetchCount          sql.NullInt64
+	LastStatusCode      sql.NullInt32
+}
+
+func (q *Queries) GetFeedHealth(ctx context.Context) ([]GetFeedHealthRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedHealth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedHealthRow
+	for rows.Next() {
+		var i GetFeedHealthRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.GoneAt,
+			&i.NextFetchAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.DisabledAt,
+			&i.FetchFullArticle,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.ImageUrl,
+			&i.Language,
+			&i.Kind,
+			&i.ScrapeRules,
+			&i.JsonMapping,
+			&i.UpdateHints,
+			&i.LastSuccessAt,
+			&i.AvgDurationMs,
+			&i.AvgItemCount,
+			&i.FetchCount,
+			&i.LastStatusCode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
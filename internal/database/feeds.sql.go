@@ -7,31 +7,40 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, fetch_full_article, kind, scrape_rules, json_mapping)
 VALUES (
            $1,
            $2,
            $3,
            $4,
             $5,
-            $6
+            $6,
+            $7,
+            $8,
+            $9,
+            $10
        )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, gone_at, next_fetch_at, consecutive_failures, last_error, disabled_at, fetch_full_article, title, description, site_url, image_url, language, kind, scrape_rules, json_mapping, update_hints
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Name             string
+	Url              string
+	UserID           uuid.UUID
+	FetchFullArticle bool
+	Kind             string
+	ScrapeRules      sql.NullString
+	JsonMapping      sql.NullString
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -42,6 +51,10 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.FetchFullArticle,
+		arg.Kind,
+		arg.ScrapeRules,
+		arg.JsonMapping,
 	)
 	var i Feed
 	err := row.Scan(
@@ -52,12 +65,70 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.GoneAt,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.DisabledAt,
+		&i.FetchFullArticle,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.ImageUrl,
+		&i.Language,
+		&i.Kind,
+		&i.ScrapeRules,
+		&i.JsonMapping,
+		&i.UpdateHints,
 	)
 	return i, err
 }
 
+const deferFeed = `-- name: DeferFeed :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       next_fetch_at = $2
+ WHERE id = $1
+`
+
+type DeferFeedParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) DeferFeed(ctx context.Context, arg DeferFeedParams) error {
+	_, err := q.db.ExecContext(ctx, deferFeed, arg.ID, arg.NextFetchAt)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+ WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       consecutive_failures = 0,
+       next_fetch_at = NULL,
+       disabled_at = NULL
+ WHERE id = $1
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, id)
+	return err
+}
+
 const getFeedByID = `-- name: GetFeedByID :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE id = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, gone_at, next_fetch_at, consecutive_failures, last_error, disabled_at, fetch_full_article, title, description, site_url, image_url, language, kind, scrape_rules, json_mapping, update_hints FROM feeds WHERE id = $1
 `
 
 func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
@@ -71,12 +142,29 @@ func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.GoneAt,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.DisabledAt,
+		&i.FetchFullArticle,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.ImageUrl,
+		&i.Language,
+		&i.Kind,
+		&i.ScrapeRules,
+		&i.JsonMapping,
+		&i.UpdateHints,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, gone_at, next_fetch_at, consecutive_failures, last_error, disabled_at, fetch_full_article, title, description, site_url, image_url, language, kind, scrape_rules, json_mapping, update_hints FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
@@ -90,12 +178,29 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.GoneAt,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.DisabledAt,
+		&i.FetchFullArticle,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.ImageUrl,
+		&i.Language,
+		&i.Kind,
+		&i.ScrapeRules,
+		&i.JsonMapping,
+		&i.UpdateHints,
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, gone_at, next_fetch_at, consecutive_failures, last_error, disabled_at, fetch_full_article, title, description, site_url, image_url, language, kind, scrape_rules, json_mapping, update_hints FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -115,6 +220,75 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.GoneAt,
+			&i.NextFetchAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.DisabledAt,
+			&i.FetchFullArticle,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.ImageUrl,
+			&i.Language,
+			&i.Kind,
+			&i.ScrapeRules,
+			&i.JsonMapping,
+			&i.UpdateHints,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsByName = `-- name: GetFeedsByName :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, gone_at, next_fetch_at, consecutive_failures, last_error, disabled_at, fetch_full_article, title, description, site_url, image_url, language, kind, scrape_rules, json_mapping, update_hints FROM feeds WHERE name = $1
+`
+
+func (q *Queries) GetFeedsByName(ctx context.Context, name string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.GoneAt,
+			&i.NextFetchAt,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.DisabledAt,
+			&i.FetchFullArticle,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.ImageUrl,
+			&i.Language,
+			&i.Kind,
+			&i.ScrapeRules,
+			&i.JsonMapping,
+			&i.UpdateHints,
 		); err != nil {
 			return nil, err
 		}
@@ -130,14 +304,17 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, gone_at, next_fetch_at, consecutive_failures, last_error, disabled_at, fetch_full_article, title, description, site_url, image_url, language, kind, scrape_rules, json_mapping, update_hints
   FROM feeds
- ORDER BY last_fetched_at DESC NULLS FIRST
+ WHERE gone_at IS NULL
+   AND disabled_at IS NULL
+   AND (next_fetch_at IS NULL OR next_fetch_at <= $1::timestamp)
+ ORDER BY last_fetched_at ASC NULLS FIRST
   LIMIT 1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+func (q *Queries) GetNextFeedToFetch(ctx context.Context, now time.Time) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getNextFeedToFetch, now)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -147,6 +324,23 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.GoneAt,
+		&i.NextFetchAt,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.DisabledAt,
+		&i.FetchFullArticle,
+		&i.Title,
+		&i.Description,
+		&i.SiteUrl,
+		&i.ImageUrl,
+		&i.Language,
+		&i.Kind,
+		&i.ScrapeRules,
+		&i.JsonMapping,
+		&i.UpdateHints,
 	)
 	return i, err
 }
@@ -162,3 +356,176 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const markFeedGone = `-- name: MarkFeedGone :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       gone_at = CURRENT_TIMESTAMP
+ WHERE id = $1
+`
+
+func (q *Queries) MarkFeedGone(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markFeedGone, id)
+	return err
+}
+
+const recordFeedFailure = `-- name: RecordFeedFailure :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       consecutive_failures = $2,
+       last_error = $3,
+       next_fetch_at = $4,
+       disabled_at = $5
+ WHERE id = $1
+`
+
+type RecordFeedFailureParams struct {
+	ID                  uuid.UUID
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	NextFetchAt         sql.NullTime
+	DisabledAt          sql.NullTime
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFailure,
+		arg.ID,
+		arg.ConsecutiveFailures,
+		arg.LastError,
+		arg.NextFetchAt,
+		arg.DisabledAt,
+	)
+	return err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       consecutive_failures = 0,
+       last_error = NULL
+ WHERE id = $1
+`
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, id)
+	return err
+}
+
+const scheduleFeed = `-- name: ScheduleFeed :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       next_fetch_at = GREATEST(COALESCE(next_fetch_at, $1::timestamp), $1::timestamp)
+ WHERE id = $2
+`
+
+type ScheduleFeedParams struct {
+	NextFetchAt time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) ScheduleFeed(ctx context.Context, arg ScheduleFeedParams) error {
+	_, err := q.db.ExecContext(ctx, scheduleFeed, arg.NextFetchAt, arg.ID)
+	return err
+}
+
+const setFeedCacheValidators = `-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       etag = $2,
+       last_modified = $3
+ WHERE id = $1
+`
+
+type SetFeedCacheValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) SetFeedCacheValidators(ctx context.Context, arg SetFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheValidators, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const setFeedFetchFullArticle = `-- name: SetFeedFetchFullArticle :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       fetch_full_article = $2
+ WHERE id = $1
+`
+
+type SetFeedFetchFullArticleParams struct {
+	ID               uuid.UUID
+	FetchFullArticle bool
+}
+
+func (q *Queries) SetFeedFetchFullArticle(ctx context.Context, arg SetFeedFetchFullArticleParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchFullArticle, arg.ID, arg.FetchFullArticle)
+	return err
+}
+
+const setFeedUpdateHints = `-- name: SetFeedUpdateHints :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       update_hints = $2
+ WHERE id = $1
+`
+
+type SetFeedUpdateHintsParams struct {
+	ID          uuid.UUID
+	UpdateHints sql.NullString
+}
+
+func (q *Queries) SetFeedUpdateHints(ctx context.Context, arg SetFeedUpdateHintsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedUpdateHints, arg.ID, arg.UpdateHints)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       title = $2,
+       description = $3,
+       site_url = $4,
+       image_url = $5,
+       language = $6
+ WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	Title       string
+	Description string
+	SiteUrl     string
+	ImageUrl    string
+	Language    string
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.SiteUrl,
+		arg.ImageUrl,
+		arg.Language,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       url = $2
+ WHERE id = $1
+`
+
+type UpdateFeedURLParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.ID, arg.Url)
+	return err
+}
@@ -11,14 +11,63 @@ import (
 	"github.com/google/uuid"
 )
 
+type Enclosure struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	PostID          uuid.UUID
+	Kind            string
+	Url             string
+	MimeType        string
+	Length          sql.NullInt64
+	DurationSeconds sql.NullInt32
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	GoneAt              sql.NullTime
+	NextFetchAt         sql.NullTime
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	DisabledAt          sql.NullTime
+	FetchFullArticle    bool
+	Title               string
+	Description         string
+	SiteUrl             string
+	ImageUrl            string
+	Language            string
+	Kind                string
+	ScrapeRules         sql.NullString
+	JsonMapping         sql.NullString
+	UpdateHints         sql.NullString
+}
+
+type FeedCredential struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	FeedID    uuid.UUID
+	Secret    string
+}
+
+type FeedFetch struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FeedID     uuid.UUID
+	StatusCode sql.NullInt32
+	DurationMs int32
+	ItemCount  int32
+	Succeeded  bool
+	Error      sql.NullString
 }
 
 type FeedFollow struct {
@@ -30,14 +79,29 @@ type FeedFollow struct {
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Title               string
+	Url                 string
+	Description         string
+	PublishedAt         time.Time
+	FeedID              uuid.UUID
+	PublishedAtInferred bool
+	Content             string
+	Author              string
+	Guid                string
+	Article             sql.NullString
+	ArticleAttempts     int32
+	ArticleNextFetchAt  sql.NullTime
+}
+
+type PostCategory struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PostID    uuid.UUID
+	Name      string
 }
 
 type User struct {
@@ -46,3 +110,15 @@ type User struct {
 	UpdatedAt time.Time
 	Name      string
 }
+
+type WebsubSubscription struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FeedID         uuid.UUID
+	Hub            string
+	Topic          string
+	Secret         string
+	LeaseExpiresAt sql.NullTime
+	NextRequestAt  time.Time
+}
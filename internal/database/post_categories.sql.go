@@ -0,0 +1,70 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: post_categories.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const createPostCategory = `-- name: CreatePostCategory :exec
+INSERT INTO post_categories(id, created_at, updated_at, post_id, name)
+VALUES (
+        $1, $2, $3, $4, $5
+       )
+ON CONFLICT (post_id, name) DO NOTHING
+`
+
+type CreatePostCategoryParams struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	PostID    uuid.UUID
+	Name      string
+}
+
+func (q *Queries) CreatePostCategory(ctx context.Context, arg CreatePostCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, createPostCategory,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.PostID,
+		arg.Name,
+	)
+	return err
+}
+
+const getCategoriesForPost = `-- name: GetCategoriesForPost :many
+SELECT name
+  FROM post_categories
+ WHERE post_id = $1
+ ORDER BY name
+`
+
+func (q *Queries) GetCategoriesForPost(ctx context.Context, postID uuid.UUID) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getCategoriesForPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
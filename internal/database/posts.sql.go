@@ -7,28 +7,45 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, published_at_inferred, feed_id, content, author, guid)
 VALUES (
-        $1, $2, $3, $4, $5, $6, $7, $8
+        $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
        )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+ON CONFLICT (feed_id, guid) DO UPDATE
+   SET updated_at = EXCLUDED.updated_at,
+       title = EXCLUDED.title,
+       url = EXCLUDED.url,
+       description = EXCLUDED.description,
+       content = EXCLUDED.content,
+       author = EXCLUDED.author,
+       published_at = CASE
+                          WHEN EXCLUDED.published_at_inferred THEN posts.published_at
+                          ELSE EXCLUDED.published_at
+                      END,
+       published_at_inferred = posts.published_at_inferred AND EXCLUDED.published_at_inferred
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, published_at_inferred, content, author, guid, article, article_attempts, article_next_fetch_at
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Title               string
+	Url                 string
+	Description         string
+	PublishedAt         time.Time
+	PublishedAtInferred bool
+	FeedID              uuid.UUID
+	Content             string
+	Author              string
+	Guid                string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -40,7 +57,11 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Url,
 		arg.Description,
 		arg.PublishedAt,
+		arg.PublishedAtInferred,
 		arg.FeedID,
+		arg.Content,
+		arg.Author,
+		arg.Guid,
 	)
 	var i Post
 	err := row.Scan(
@@ -52,16 +73,24 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.PublishedAtInferred,
+		&i.Content,
+		&i.Author,
+		&i.Guid,
+		&i.Article,
+		&i.ArticleAttempts,
+		&i.ArticleNextFetchAt,
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.published_at_inferred, posts.content, posts.author, posts.guid, posts.article, posts.article_attempts, posts.article_next_fetch_at
   FROM posts
  INNER JOIN feed_follows
     ON posts.feed_id = feed_follows.feed_id
  WHERE feed_follows.user_id = $1
+ ORDER BY posts.published_at DESC
  LIMIT $2
 `
 
@@ -88,6 +117,13 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.PublishedAtInferred,
+			&i.Content,
+			&i.Author,
+			&i.Guid,
+			&i.Article,
+			&i.ArticleAttempts,
+			&i.ArticleNextFetchAt,
 		); err != nil {
 			return nil, err
 		}
@@ -101,3 +137,142 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getPostsWithoutArticle = `-- name: GetPostsWithoutArticle :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.published_at_inferred, posts.content, posts.author, posts.guid, posts.article, posts.article_attempts, posts.article_next_fetch_at,
+       feeds.url AS feed_url
+  FROM posts
+ INNER JOIN feeds
+    ON feeds.id = posts.feed_id
+ WHERE feeds.fetch_full_article
+   AND posts.article IS NULL
+   AND posts.url <> ''
+   AND posts.article_attempts < $1
+   AND (posts.article_next_fetch_at IS NULL OR posts.article_next_fetch_at <= $2::timestamp)
+ ORDER BY posts.published_at DESC
+ LIMIT $3
+`
+
+type GetPostsWithoutArticleParams struct {
+	MaxAttempts int32
+	Now         time.Time
+	PostLimit   int32
+}
+
+type GetPostsWithoutArticleRow struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Title               string
+	Url                 string
+	Description         string
+	PublishedAt         time.Time
+	FeedID              uuid.UUID
+	PublishedAtInferred bool
+	Content             string
+	Author              string
+	Guid                string
+	Article             sql.NullString
+	ArticleAttempts     int32
+	ArticleNextFetchAt  sql.NullTime
+	FeedUrl             string
+}
+
+func (q *Queries) GetPostsWithoutArticle(ctx context.Context, arg GetPostsWithoutArticleParams) ([]GetPostsWithoutArticleRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsWithoutArticle, arg.MaxAttempts, arg.Now, arg.PostLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsWithoutArticleRow
+	for rows.Next() {
+		var i GetPostsWithoutArticleRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.PublishedAtInferred,
+			&i.Content,
+			&i.Author,
+			&i.Guid,
+			&i.Article,
+			&i.ArticleAttempts,
+			&i.ArticleNextFetchAt,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+   SET updated_at = CURRENT_TIMESTAMP,
+       feed_id = $1
+ WHERE posts.feed_id = $2
+   AND NOT EXISTS (
+           SELECT 1
+             FROM posts AS existing
+            WHERE existing.feed_id = $1
+              AND existing.guid = posts.guid
+       )
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const recordPostArticleFailure = `-- name: RecordPostArticleFailure :exec
+UPDATE posts
+   SET updated_at = CURRENT_TIMESTAMP,
+       article_attempts = $2,
+       article_next_fetch_at = $3
+ WHERE id = $1
+`
+
+type RecordPostArticleFailureParams struct {
+	ID                 uuid.UUID
+	ArticleAttempts    int32
+	ArticleNextFetchAt sql.NullTime
+}
+
+func (q *Queries) RecordPostArticleFailure(ctx context.Context, arg RecordPostArticleFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordPostArticleFailure, arg.ID, arg.ArticleAttempts, arg.ArticleNextFetchAt)
+	return err
+}
+
+const setPostArticle = `-- name: SetPostArticle :exec
+UPDATE posts
+   SET updated_at = CURRENT_TIMESTAMP,
+       article = $2
+ WHERE id = $1
+`
+
+type SetPostArticleParams struct {
+	ID      uuid.UUID
+	Article sql.NullString
+}
+
+func (q *Queries) SetPostArticle(ctx context.Context, arg SetPostArticleParams) error {
+	_, err := q.db.ExecContext(ctx, setPostArticle, arg.ID, arg.Article)
+	return err
+}
@@ -0,0 +1,70 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Querier interface {
+	ActivateWebSubSubscription(ctx context.Context, arg ActivateWebSubSubscriptionParams) error
+	CreateEnclosure(ctx context.Context, arg CreateEnclosureParams) error
+	CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error)
+	CreateFeedFetch(ctx context.Context, arg CreateFeedFetchParams) error
+	CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error)
+	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
+	CreatePostCategory(ctx context.Context, arg CreatePostCategoryParams) error
+	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeferFeed(ctx context.Context, arg DeferFeedParams) error
+	DeleteFeed(ctx context.Context, id uuid.UUID) error
+	DeleteFeedCredentials(ctx context.Context, feedID uuid.UUID) error
+	DeleteFeedFetchesBefore(ctx context.Context, createdAt time.Time) error
+	DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowParams) error
+	DeleteFeedFollowsForFeed(ctx context.Context, feedID uuid.UUID) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeleteUserByName(ctx context.Context, name string) error
+	DeleteUsers(ctx context.Context) error
+	EnableFeed(ctx context.Context, id uuid.UUID) error
+	GetCategoriesForPost(ctx context.Context, postID uuid.UUID) ([]string, error)
+	GetEnclosuresForPost(ctx context.Context, postID uuid.UUID) ([]Enclosure, error)
+	GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error)
+	GetFeedByURL(ctx context.Context, url string) (Feed, error)
+	GetFeedCredentials(ctx context.Context, feedID uuid.UUID) (FeedCredential, error)
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error)
+	GetFeedHealth(ctx context.Context) ([]GetFeedHealthRow, error)
+	GetFeeds(ctx context.Context) ([]Feed, error)
+	GetFeedsByName(ctx context.Context, name string) ([]Feed, error)
+	GetNextFeedToFetch(ctx context.Context, now time.Time) (Feed, error)
+	GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error)
+	GetPostsWithoutArticle(ctx context.Context, arg GetPostsWithoutArticleParams) ([]GetPostsWithoutArticleRow, error)
+	GetUser(ctx context.Context, id uuid.UUID) (User, error)
+	GetUserByName(ctx context.Context, name string) (User, error)
+	GetUsers(ctx context.Context) ([]User, error)
+	GetWebSubSubscription(ctx context.Context, id uuid.UUID) (WebsubSubscription, error)
+	GetWebSubSubscriptionForFeed(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error)
+	GetWebSubSubscriptionsDue(ctx context.Context, now time.Time) ([]WebsubSubscription, error)
+	MarkFeedFetched(ctx context.Context, id uuid.UUID) error
+	MarkFeedGone(ctx context.Context, id uuid.UUID) error
+	MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error
+	MovePosts(ctx context.Context, arg MovePostsParams) error
+	RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) error
+	RecordFeedSuccess(ctx context.Context, id uuid.UUID) error
+	RecordPostArticleFailure(ctx context.Context, arg RecordPostArticleFailureParams) error
+	ScheduleFeed(ctx context.Context, arg ScheduleFeedParams) error
+	SetFeedCacheValidators(ctx context.Context, arg SetFeedCacheValidatorsParams) error
+	SetFeedFetchFullArticle(ctx context.Context, arg SetFeedFetchFullArticleParams) error
+	SetFeedUpdateHints(ctx context.Context, arg SetFeedUpdateHintsParams) error
+	SetPostArticle(ctx context.Context, arg SetPostArticleParams) error
+	SetWebSubNextRequest(ctx context.Context, arg SetWebSubNextRequestParams) error
+	UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error
+	UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error
+	UpsertFeedCredentials(ctx context.Context, arg UpsertFeedCredentialsParams) error
+	UpsertWebSubSubscription(ctx context.Context, arg UpsertWebSubSubscriptionParams) (WebsubSubscription, error)
+}
+
+var _ Querier = (*Queries)(nil)
@@ -0,0 +1,191 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: websub_subscriptions.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const activateWebSubSubscription = `-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+   SET updated_at = CURRENT_TIMESTAMP,
+       lease_expires_at = $2,
+       next_request_at = $3
+ WHERE id = $1
+`
+
+type ActivateWebSubSubscriptionParams struct {
+	ID             uuid.UUID
+	LeaseExpiresAt sql.NullTime
+	NextRequestAt  time.Time
+}
+
+func (q *Queries) ActivateWebSubSubscription(ctx context.Context, arg ActivateWebSubSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, activateWebSubSubscription, arg.ID, arg.LeaseExpiresAt, arg.NextRequestAt)
+	return err
+}
+
+const getWebSubSubscription = `-- name: GetWebSubSubscription :one
+SELECT id, created_at, updated_at, feed_id, hub, topic, secret, lease_expires_at, next_request_at
+  FROM websub_subscriptions
+ WHERE id = $1
+`
+
+func (q *Queries) GetWebSubSubscription(ctx context.Context, id uuid.UUID) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebSubSubscription, id)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.Hub,
+		&i.Topic,
+		&i.Secret,
+		&i.LeaseExpiresAt,
+		&i.NextRequestAt,
+	)
+	return i, err
+}
+
+const getWebSubSubscriptionForFeed = `-- name: GetWebSubSubscriptionForFeed :one
+SELECT id, created_at, updated_at, feed_id, hub, topic, secret, lease_expires_at, next_request_at
+  FROM websub_subscriptions
+ WHERE feed_id = $1
+`
+
+func (q *Queries) GetWebSubSubscriptionForFeed(ctx context.Context, feedID uuid.UUID) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, getWebSubSubscriptionForFeed, feedID)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.Hub,
+		&i.Topic,
+		&i.Secret,
+		&i.LeaseExpiresAt,
+		&i.NextRequestAt,
+	)
+	return i, err
+}
+
+const getWebSubSubscriptionsDue = `-- name: GetWebSubSubscriptionsDue :many
+SELECT websub_subscriptions.id, websub_subscriptions.created_at, websub_subscriptions.updated_at, websub_subscriptions.feed_id, websub_subscriptions.hub, websub_subscriptions.topic, websub_subscriptions.secret, websub_subscriptions.lease_expires_at, websub_subscriptions.next_request_at
+  FROM websub_subscriptions
+  JOIN feeds ON feeds.id = websub_subscriptions.feed_id
+ WHERE websub_subscriptions.next_request_at <= $1::timestamp
+   AND feeds.gone_at IS NULL
+   AND feeds.disabled_at IS NULL
+ ORDER BY websub_subscriptions.next_request_at
+`
+
+func (q *Queries) GetWebSubSubscriptionsDue(ctx context.Context, now time.Time) ([]WebsubSubscription, error) {
+	rows, err := q.db.QueryContext(ctx, getWebSubSubscriptionsDue, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebsubSubscription
+	for rows.Next() {
+		var i WebsubSubscription
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedID,
+			&i.Hub,
+			&i.Topic,
+			&i.Secret,
+			&i.LeaseExpiresAt,
+			&i.NextRequestAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setWebSubNextRequest = `-- name: SetWebSubNextRequest :exec
+UPDATE websub_subscriptions
+   SET updated_at = CURRENT_TIMESTAMP,
+       next_request_at = $2
+ WHERE id = $1
+`
+
+type SetWebSubNextRequestParams struct {
+	ID            uuid.UUID
+	NextRequestAt time.Time
+}
+
+func (q *Queries) SetWebSubNextRequest(ctx context.Context, arg SetWebSubNextRequestParams) error {
+	_, err := q.db.ExecContext(ctx, setWebSubNextRequest, arg.ID, arg.NextRequestAt)
+	return err
+}
+
+const upsertWebSubSubscription = `-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions(id, created_at, updated_at, feed_id, hub, topic, secret, next_request_at)
+VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8
+       )
+ON CONFLICT (feed_id) DO UPDATE
+   SET updated_at = EXCLUDED.updated_at,
+       hub = EXCLUDED.hub,
+       topic = EXCLUDED.topic,
+       secret = EXCLUDED.secret,
+       lease_expires_at = NULL,
+       next_request_at = EXCLUDED.next_request_at
+RETURNING id, created_at, updated_at, feed_id, hub, topic, secret, lease_expires_at, next_request_at
+`
+
+type UpsertWebSubSubscriptionParams struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	FeedID        uuid.UUID
+	Hub           string
+	Topic         string
+	Secret        string
+	NextRequestAt time.Time
+}
+
+func (q *Queries) UpsertWebSubSubscription(ctx context.Context, arg UpsertWebSubSubscriptionParams) (WebsubSubscription, error) {
+	row := q.db.QueryRowContext(ctx, upsertWebSubSubscription,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FeedID,
+		arg.Hub,
+		arg.Topic,
+		arg.Secret,
+		arg.NextRequestAt,
+	)
+	var i WebsubSubscription
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FeedID,
+		&i.Hub,
+		&i.Topic,
+		&i.Secret,
+		&i.LeaseExpiresAt,
+		&i.NextRequestAt,
+	)
+	return i, err
+}
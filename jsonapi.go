@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const feedKindJSON = "json"
+
+type jsonMapping struct {
+	Items string `json:"items,omitempty"`
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Date  string `json:"date,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+type jsonMappingFlags struct {
+	mapping jsonMapping
+	set     bool
+}
+
+func (f *jsonMappingFlags) register(fs *flag.FlagSet) {
+	fs.Func("json-items", "path to the array of posts in a JSON API response, empty for the top level", func(value string) error {
+		f.mapping.Items = value
+		f.set = true
+		return nil
+	})
+	fs.StringVar(&f.mapping.ID, "json-id", "", "path to the unique id of a post within an item")
+	fs.StringVar(&f.mapping.Title, "json-title", "", "path to the post title within an item")
+	fs.StringVar(&f.mapping.URL, "json-url", "", "path to the post link within an item")
+	fs.StringVar(&f.mapping.Date, "json-date", "", "path to the post date within an item")
+	fs.StringVar(&f.mapping.Body, "json-body", "", "path to the post body within an item")
+}
+
+func (f *jsonMappingFlags) isSet() bool {
+	return f.set || f.mapping != jsonMapping{}
+}
+
+func (m jsonMapping) validate() error {
+	if m.Title == "" && m.URL == "" {
+		return errors.New("--json-title or --json-url is required to map a JSON API")
+	}
+	return nil
+}
+
+func (m jsonMapping) parse(p *page) (*RSSFeed, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(p.Data))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	value, ok := lookupJSONPath(doc, m.Items)
+	if !ok {
+		return nil, fmt.Errorf("path %q not found in response", m.Items)
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("path %q is not an array", m.Items)
+	}
+
+	feed := &RSSFeed{}
+	feed.Channel.Link = p.URL
+	for _, entry := range items {
+		item := RSSItem{
+			GUID:        jsonPathString(entry, m.ID),
+			Title:       jsonPathString(entry, m.Title),
+			Link:        resolveReference(p.URL, jsonPathString(entry, m.URL)),
+			Description: jsonPathString(entry, m.Body),
+			PubDate:     jsonPathDate(entry, m.Date),
+		}
+		if item.Title == "" && item.Link == "" {
+			continue
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return feed, nil
+}
+
+// lookupJSONPath follows a dot separated path through objects and arrays, e.g.
+// "data.releases" or "assets.0.name". An empty path returns the value itself.
+func lookupJSONPath(value any, path string) (any, bool) {
+	if path == "" {
+		return value, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func jsonPathString(item any, path string) string {
+	if path == "" {
+		return ""
+	}
+	value, ok := lookupJSONPath(item, path)
+	if !ok || value == nil {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
+
+func jsonPathDate(item any, path string) string {
+	if path == "" {
+		return ""
+	}
+	value, ok := lookupJSONPath(item, path)
+	if !ok {
+		return ""
+	}
+	if n, ok := value.(json.Number); ok {
+		seconds, err := n.Float64()
+		if err != nil {
+			return ""
+		}
+		// Timestamps in milliseconds are common in JSON APIs.
+		if seconds > 1e11 {
+			seconds /= 1000
+		}
+		whole, frac := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(frac*1e9)).UTC().Format(time.RFC3339Nano)
+	}
+	return jsonPathString(item, path)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Language    string         `json:"language"`
+	Hubs        []JSONFeedHub  `json:"hubs"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type JSONFeedItem struct {
+	ID            JSONFeedID           `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Author        *JSONFeedAuthor      `json:"author"`
+	Image         string               `json:"image"`
+	Tags          []string             `json:"tags"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+}
+
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// JSONFeedID accepts both strings and numbers, as JSON Feed 1.0 publishers
+// frequently emit numeric ids.
+type JSONFeedID string
+
+func (id *JSONFeedID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = JSONFeedID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = JSONFeedID(n.String())
+	return nil
+}
+
+func isJSONFeed(data []byte) bool {
+	var probe struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return false
+	}
+	return strings.Contains(probe.Version, "jsonfeed.org/version/")
+}
+
+func parseJSONFeed(data []byte) (*RSSFeed, error) {
+	jf := &JSONFeed{}
+	if err := json.Unmarshal(data, jf); err != nil {
+		return nil, err
+	}
+
+	feed := &RSSFeed{}
+	feed.Channel.Title = jf.Title
+	feed.Channel.Link = jf.HomePageURL
+	feed.Channel.Description = jf.Description
+	feed.Channel.Language = jf.Language
+	feed.Channel.Self = jf.FeedURL
+	for _, hub := range jf.Hubs {
+		if strings.EqualFold(hub.Type, "WebSub") && hub.URL != "" {
+			feed.Channel.Hubs = append(feed.Channel.Hubs, hub.URL)
+		}
+	}
+	feed.Channel.Image.URL = jf.Icon
+	if feed.Channel.Image.URL == "" {
+		feed.Channel.Image.URL = jf.Favicon
+	}
+	for _, entry := range jf.Items {
+		item := RSSItem{
+			Title:       entry.Title,
+			Link:        entry.URL,
+			Description: entry.Summary,
+			PubDate:     entry.DatePublished,
+			GUID:        string(entry.ID),
+			Author:      jsonFeedAuthors(entry),
+			Content:     entry.ContentHTML,
+			Categories:  entry.Tags,
+		}
+		if item.Content == "" {
+			item.Content = entry.ContentText
+		}
+		for _, attachment := range entry.Attachments {
+			enclosure := RSSEnclosure{
+				URL:  attachment.URL,
+				Type: attachment.MimeType,
+			}
+			if attachment.SizeInBytes > 0 {
+				enclosure.Length = strconv.FormatInt(attachment.SizeInBytes, 10)
+			}
+			if attachment.DurationInSeconds > 0 {
+				enclosure.Duration = strconv.FormatFloat(attachment.DurationInSeconds, 'f', -1, 64)
+			}
+			item.Enclosures = append(item.Enclosures, enclosure)
+		}
+		if entry.Image != "" {
+			item.MediaThumbnails = append(item.MediaThumbnails, RSSEnclosure{URL: entry.Image})
+		}
+		if item.Link == "" {
+			item.Link = entry.ExternalURL
+		}
+		if item.Description == "" {
+			item.Description = item.Content
+		}
+		if item.PubDate == "" {
+			item.PubDate = entry.DateModified
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return feed, nil
+}
+
+func jsonFeedAuthors(item JSONFeedItem) string {
+	authors := item.Authors
+	if len(authors) == 0 && item.Author != nil {
+		authors = append(authors, *item.Author)
+	}
+
+	var names []string
+	for _, author := range authors {
+		if author.Name != "" {
+			names = append(names, author.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,115 @@
+package main
+
+import "testing"
+
+func TestParseFeedDetectsJSONFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+		wantErr     bool
+	}{
+		{
+			name:        "content type without version",
+			data:        `{"title": "Typed", "items": []}`,
+			contentType: "application/feed+json; charset=utf-8",
+		},
+		{
+			name:        "version sniffed from a generic content type",
+			data:        `{"version": "https://jsonfeed.org/version/1.1", "title": "Typed", "items": []}`,
+			contentType: "application/json",
+		},
+		{
+			name:        "plain JSON is not a feed",
+			data:        `{"title": "Typed", "items": []}`,
+			contentType: "application/json",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.data), tt.contentType)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseFeed succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFeed failed: %v", err)
+			}
+			if feed.Channel.Title != "Typed" {
+				t.Errorf("title = %q", feed.Channel.Title)
+			}
+		})
+	}
+}
+
+func TestParseJSONFeedItems(t *testing.T) {
+	data := `{
+		"version": "https://jsonfeed.org/version/1",
+		"title": "Example JSON",
+		"home_page_url": "https://example.com/",
+		"feed_url": "https://example.com/feed.json",
+		"items": [
+			{
+				"id": 42,
+				"url": "https://example.com/posts/42",
+				"title": "Numeric id",
+				"content_html": "<p>Body</p>",
+				"date_published": "2006-01-02T15:04:05Z",
+				"date_modified": "2006-01-03T15:04:05Z",
+				"author": {"name": "Old Style"}
+			},
+			{
+				"id": "second",
+				"external_url": "https://elsewhere.example.org/story",
+				"title": "External link",
+				"summary": "Summary",
+				"date_modified": "2006-01-04T15:04:05Z",
+				"authors": [{"name": "Jane"}, {"name": "John"}],
+				"author": {"name": "Ignored"}
+			}
+		]
+	}`
+
+	feed, err := parseFeed([]byte(data), "application/json")
+	if err != nil {
+		t.Fatalf("parseFeed failed: %v", err)
+	}
+	if feed.Channel.Link != "https://example.com/" || feed.Channel.Self != "https://example.com/feed.json" {
+		t.Errorf("channel link = %q, self = %q", feed.Channel.Link, feed.Channel.Self)
+	}
+	if len(feed.Channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Channel.Item))
+	}
+
+	first := feed.Channel.Item[0]
+	if first.GUID != "42" {
+		t.Errorf("first guid = %q, want the numeric id as a string", first.GUID)
+	}
+	if first.Author != "Old Style" {
+		t.Errorf("first author = %q, want the JSON Feed 1.0 author", first.Author)
+	}
+	if first.Description != "<p>Body</p>" {
+		t.Errorf("first description = %q, want the content fallback", first.Description)
+	}
+	if first.PubDate != "2006-01-02T15:04:05Z" {
+		t.Errorf("first pubDate = %q, want date_published", first.PubDate)
+	}
+
+	second := feed.Channel.Item[1]
+	if second.GUID != "second" {
+		t.Errorf("second guid = %q", second.GUID)
+	}
+	if second.Link != "https://elsewhere.example.org/story" {
+		t.Errorf("second link = %q, want the external_url fallback", second.Link)
+	}
+	if second.Author != "Jane, John" {
+		t.Errorf("second author = %q, want authors over author", second.Author)
+	}
+	if second.PubDate != "2006-01-04T15:04:05Z" {
+		t.Errorf("second pubDate = %q, want the date_modified fallback", second.PubDate)
+	}
+}
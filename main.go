@@ -12,8 +12,10 @@ import (
 )
 
 type state struct {
-	cfg *config.Config
-	db  *database.Queries
+	cfg     *config.Config
+	db      database.Querier
+	hosts   *hostLimiter
+	fetcher Fetcher
 }
 
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
@@ -40,9 +42,16 @@ func main() {
 	defer db.Close()
 	dbQueries := database.New(db)
 
+	hostInterval, err := cfg.HostInterval()
+	if err != nil {
+		log.Fatalf("error reading host_min_interval: %v", err)
+	}
+
 	s := &state{
-		cfg: &cfg,
-		db:  dbQueries,
+		cfg:     &cfg,
+		db:      dbQueries,
+		hosts:   newHostLimiter(hostInterval),
+		fetcher: newHTTPFetcher(nil, cfg.MaxFeedBytes),
 	}
 
 	c := commands{
@@ -54,7 +63,11 @@ func main() {
 	c.register("users", handlerUsers)
 	c.register("agg", handlerAggregate)
 	c.register("addfeed", middlewareLoggedIn(handlerAddFeed))
+	c.register("editfeed", middlewareLoggedIn(handlerEditFeed))
 	c.register("feeds", handlerFeeds)
+	c.register("enablefeed", middlewareLoggedIn(handlerEnableFeed))
+	c.register("feedhealth", handlerFeedHealth)
+	c.register("ingest", middlewareLoggedIn(handlerIngest))
 	c.register("follow", middlewareLoggedIn(handlerFollowFeed))
 	c.register("following", middlewareLoggedIn(handlerFeedsFollowing))
 	c.register("unfollow", middlewareLoggedIn(handlerUnfollowFeed))
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultHostInterval = 5 * time.Second
+
+const defaultRetryAfter = 5 * time.Minute
+
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+	deferred map[string]time.Time
+}
+
+type hostDeferredError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *hostDeferredError) Error() string {
+	return fmt.Sprintf("requests to %s are deferred until %v", e.Host, e.Until.Format(time.RFC3339))
+}
+
+func newHostLimiter(interval time.Duration) *hostLimiter {
+	if interval <= 0 {
+		interval = defaultHostInterval
+	}
+	return &hostLimiter{
+		interval: interval,
+		next:     make(map[string]time.Time),
+		deferred: make(map[string]time.Time),
+	}
+}
+
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	if host == "" {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if until, ok := l.deferred[host]; ok {
+		if until.After(now) {
+			l.mu.Unlock()
+			return &hostDeferredError{Host: host, Until: until}
+		}
+		delete(l.deferred, host)
+	}
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (l *hostLimiter) deferHost(host string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.deferred[host]) {
+		l.deferred[host] = until
+	}
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(u.Host)
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
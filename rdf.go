@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+type RDFFeed struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"channel"`
+	Image struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+	Items []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+func parseRDFFeed(decoder *xml.Decoder, root xml.StartElement) (*RSSFeed, error) {
+	rdf := &RDFFeed{}
+	if err := decoder.DecodeElement(rdf, &root); err != nil {
+		return nil, err
+	}
+
+	feed := &RSSFeed{}
+	feed.Channel.Title = strings.TrimSpace(rdf.Channel.Title)
+	feed.Channel.Link = strings.TrimSpace(rdf.Channel.Link)
+	feed.Channel.Description = strings.TrimSpace(rdf.Channel.Description)
+	feed.Channel.Language = strings.TrimSpace(rdf.Channel.Language)
+	feed.Channel.UpdatePeriod = rdf.Channel.UpdatePeriod
+	feed.Channel.UpdateFrequency = rdf.Channel.UpdateFrequency
+	feed.Channel.Image.URL = strings.TrimSpace(rdf.Image.URL)
+	for _, entry := range rdf.Items {
+		item := RSSItem{
+			Title:       strings.TrimSpace(entry.Title),
+			Link:        strings.TrimSpace(entry.Link),
+			Description: strings.TrimSpace(entry.Description),
+			PubDate:     strings.TrimSpace(entry.Date),
+			GUID:        entry.About,
+			Author:      strings.TrimSpace(entry.Creator),
+			Content:     entry.Content,
+			Categories:  entry.Subjects,
+		}
+		if item.Link == "" {
+			item.Link = entry.About
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return feed, nil
+}
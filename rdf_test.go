@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestParseRDFFeed(t *testing.T) {
+	feed, err := parseFeed(readFixture(t, "rdf.xml"), "application/rdf+xml")
+	if err != nil {
+		t.Fatalf("parseFeed failed: %v", err)
+	}
+
+	if feed.Channel.Title != "Example RDF" || feed.Channel.Link != "https://example.com/" {
+		t.Errorf("channel = %q / %q", feed.Channel.Title, feed.Channel.Link)
+	}
+	if feed.Channel.Language != "en" {
+		t.Errorf("language = %q", feed.Channel.Language)
+	}
+	if len(feed.Channel.Item) != 2 {
+		t.Fatalf("got %d items, want the 2 items next to <channel>", len(feed.Channel.Item))
+	}
+
+	first := feed.Channel.Item[0]
+	if first.Link != "https://example.com/items/1?utm=rss" {
+		t.Errorf("first link = %q", first.Link)
+	}
+	if first.GUID != "https://example.com/items/1" {
+		t.Errorf("first guid = %q, want rdf:about", first.GUID)
+	}
+	if first.PubDate != "2006-01-02T15:04:05Z" || first.Author != "Jane Doe" {
+		t.Errorf("first pubDate = %q, author = %q, want dc:date and dc:creator", first.PubDate, first.Author)
+	}
+	if first.Description != "First description" {
+		t.Errorf("first description = %q", first.Description)
+	}
+
+	second := feed.Channel.Item[1]
+	if second.Link != "https://example.com/items/2" || second.GUID != "https://example.com/items/2" {
+		t.Errorf("second link = %q, guid = %q, want the rdf:about fallback", second.Link, second.GUID)
+	}
+	if second.PubDate != "2006-01-03T15:04:05+01:00" {
+		t.Errorf("second pubDate = %q", second.PubDate)
+	}
+}
@@ -1,63 +1,217 @@
 package main
 
 import (
-	"context"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"html"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
 	"time"
+
+	"golang.org/x/net/html/charset"
 )
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title       string     `xml:"title"`
+		AtomLinks   []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Link        string     `xml:"link"`
+		Description string     `xml:"description"`
+		Language    string     `xml:"language"`
+		ItunesImage struct {
+			Href string `xml:"href,attr"`
+		} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		Image struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+		Item []RSSItem `xml:"item"`
+
+		TTL             string   `xml:"ttl"`
+		SkipHours       []string `xml:"skipHours>hour"`
+		SkipDays        []string `xml:"skipDays>day"`
+		UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+
+		Hubs []string `xml:"-"`
+		Self string   `xml:"-"`
 	} `xml:"channel"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	GUID        string   `xml:"guid"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Categories  []string `xml:"category"`
+
+	Enclosures      []RSSEnclosure `xml:"enclosure"`
+	MediaContent    []RSSEnclosure `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails []RSSEnclosure `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroups     []struct {
+		Content    []RSSEnclosure `xml:"http://search.yahoo.com/mrss/ content"`
+		Thumbnails []RSSEnclosure `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+	ItunesDuration string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
 }
 
-func fetchFeed(ctx context.Context, url string) (*RSSFeed, error) {
-	httpClient := &http.Client{
-		Timeout: time.Second * 10,
+func (item RSSItem) identity() string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
 	}
+	sum := sha256.Sum256([]byte(item.Title + "\n" + item.Description + "\n" + item.Content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+type RSSEnclosure struct {
+	URL      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Length   string `xml:"length,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Duration string `xml:"duration,attr"`
+}
+
+type fetchRequest struct {
+	URL          string
+	ETag         string
+	LastModified string
+	MaxBytes     int64
+	Header       http.Header
+	Parse        func(p *page) (*RSSFeed, error)
+}
+
+type fetchResult struct {
+	StatusCode   int
+	Feed         *RSSFeed
+	NotModified  bool
+	ETag         string
+	LastModified string
+	PermanentURL string
+	Hub          string
+	Topic        string
+}
+
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+	// Throttled is set when the server asked to come back after RetryAfter,
+	// which may be zero.
+	Throttled  bool
+	RetryAfter time.Duration
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("unexpected response status: %s", e.Status)
+}
+
+func parseFeed(data []byte, contentType string) (*RSSFeed, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/feed+json" || isJSONFeed(data) {
+		return parseJSONFeed(data)
 	}
 
-	req.Header.Set("User-Agent", "gator")
-	resp, err := httpClient.Do(req)
+	decoder, err := newXMLDecoder(data, contentType)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	data, err := io.ReadAll(resp.Body)
+	root, err := rootElement(decoder)
 	if err != nil {
 		return nil, err
 	}
-	
-	feed := &RSSFeed{}
-	if err := xml.Unmarshal(data, feed); err != nil {
+
+	switch root.Name.Local {
+	case "rss":
+		feed := &RSSFeed{}
+		if err := decoder.DecodeElement(feed, &root); err != nil {
+			return nil, err
+		}
+		for i, item := range feed.Channel.Item {
+			if item.Creator != "" {
+				feed.Channel.Item[i].Author = item.Creator
+			}
+		}
+		if feed.Channel.Image.URL == "" {
+			feed.Channel.Image.URL = feed.Channel.ItunesImage.Href
+		}
+		feed.Channel.Hubs, feed.Channel.Self = hubLinks(feed.Channel.AtomLinks)
+		return feed, nil
+	case "feed":
+		return parseAtomFeed(decoder, root)
+	case "RDF":
+		return parseRDFFeed(decoder, root)
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root.Name.Local)
+	}
+}
+
+// parseFeedPage parses a syndication feed, whose titles and descriptions are
+// often escaped twice. Scraped pages and mapped JSON are taken as they are.
+func parseFeedPage(p *page) (*RSSFeed, error) {
+	feed, err := parseFeed(p.Data, p.ContentType)
+	if err != nil {
 		return nil, err
 	}
+	unescapeFeed(feed)
+	return feed, nil
+}
 
+func unescapeFeed(feed *RSSFeed) {
 	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
 	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
 	for i, item := range feed.Channel.Item {
 		feed.Channel.Item[i].Title = html.UnescapeString(item.Title)
 		feed.Channel.Item[i].Description = html.UnescapeString(item.Description)
 	}
+}
+
+func newXMLDecoder(data []byte, contentType string) (*xml.Decoder, error) {
+	var r io.Reader = bytes.NewReader(data)
+
+	_, params, _ := mime.ParseMediaType(contentType)
+	label := params["charset"]
+	if label == "" {
+		decoder := xml.NewDecoder(r)
+		decoder.CharsetReader = charset.NewReaderLabel
+		return decoder, nil
+	}
 
-	return feed, nil
+	if !strings.EqualFold(label, "utf-8") && !strings.EqualFold(label, "utf8") {
+		transcoded, err := charset.NewReaderLabel(label, r)
+		if err != nil {
+			return nil, err
+		}
+		r = transcoded
+	}
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder, nil
+}
+
+func rootElement(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return xml.StartElement{}, errors.New("unrecognized feed document")
+		}
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
 }
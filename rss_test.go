@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestNewXMLDecoderCharsets(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+		want        string
+	}{
+		{
+			name:        "charset declared in the prolog",
+			data:        "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<rss><channel><title>Caf\xe9</title></channel></rss>",
+			contentType: "application/rss+xml",
+			want:        "Café",
+		},
+		{
+			name:        "charset declared in the content type",
+			data:        "<?xml version=\"1.0\"?>\n<rss><channel><title>\x80 5 \x96 caf\xe9</title></channel></rss>",
+			contentType: "application/rss+xml; charset=windows-1252",
+			want:        "€ 5 – café",
+		},
+		{
+			name:        "content type overrides the prolog",
+			data:        "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<rss><channel><title>Caf\xe9</title></channel></rss>",
+			contentType: "text/xml; charset=ISO-8859-1",
+			want:        "Café",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.data), tt.contentType)
+			if err != nil {
+				t.Fatalf("parseFeed failed: %v", err)
+			}
+			if feed.Channel.Title != tt.want {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timpinoy/bd-aggregator/internal/database"
+)
+
+const maxHintInterval = 365 * 24 * time.Hour
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+type updateHints struct {
+	IntervalSeconds int64    `json:"interval_seconds,omitempty"`
+	SkipHours       []int    `json:"skip_hours,omitempty"`
+	SkipDays        []string `json:"skip_days,omitempty"`
+}
+
+func feedUpdateHints(feed *RSSFeed) updateHints {
+	var interval time.Duration
+	if ttl, err := strconv.Atoi(strings.TrimSpace(feed.Channel.TTL)); err == nil && ttl > 0 {
+		interval = time.Duration(ttl) * time.Minute
+	}
+
+	period := strings.ToLower(strings.TrimSpace(feed.Channel.UpdatePeriod))
+	frequencyValue := strings.TrimSpace(feed.Channel.UpdateFrequency)
+	if period != "" || frequencyValue != "" {
+		if period == "" {
+			period = "daily"
+		}
+		frequency, err := strconv.Atoi(frequencyValue)
+		if err != nil || frequency <= 0 {
+			frequency = 1
+		}
+		if length, ok := updatePeriods[period]; ok {
+			interval = max(interval, length/time.Duration(frequency))
+		}
+	}
+
+	hints := updateHints{
+		IntervalSeconds: int64(min(interval, maxHintInterval) / time.Second),
+	}
+	for _, value := range feed.Channel.SkipHours {
+		// skipHours uses 0-23, but some publishers write 24 for midnight.
+		if hour, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && hour >= 0 && hour <= 24 {
+			hints.SkipHours = append(hints.SkipHours, hour%24)
+		}
+	}
+	for _, value := range feed.Channel.SkipDays {
+		day := strings.ToLower(strings.TrimSpace(value))
+		if _, ok := weekdays[day]; ok {
+			hints.SkipDays = append(hints.SkipDays, day)
+		}
+	}
+	return hints
+}
+
+func (h updateHints) isEmpty() bool {
+	return h.IntervalSeconds == 0 && len(h.SkipHours) == 0 && len(h.SkipDays) == 0
+}
+
+// nextFetch returns the earliest time the publisher allows the feed to be
+// fetched again. skipHours and skipDays are in GMT, as the RSS spec defines.
+func (h updateHints) nextFetch(now time.Time) time.Time {
+	next := now.UTC().Add(time.Duration(h.IntervalSeconds) * time.Second)
+	for i := 0; i < 8*24 && h.skipped(next); i++ {
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+	return next
+}
+
+func (h updateHints) skipped(t time.Time) bool {
+	for _, hour := range h.SkipHours {
+		if t.Hour() == hour {
+			return true
+		}
+	}
+	for _, day := range h.SkipDays {
+		if weekdays[day] == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// notBeforeHints returns t, or the time the feed's stored hints allow the
+// next fetch if that is later, so retries don't poll faster than requested.
+func notBeforeHints(feed database.Feed, now, t time.Time) time.Time {
+	if next := loadUpdateHints(feed).nextFetch(now); next.After(t) {
+		return next
+	}
+	return t
+}
+
+func loadUpdateHints(feed database.Feed) updateHints {
+	var hints updateHints
+	if feed.UpdateHints.Valid {
+		if err := json.Unmarshal([]byte(feed.UpdateHints.String), &hints); err != nil {
+			log.Printf("invalid update hints of %s: %v", feed.Name, err)
+		}
+	}
+	return hints
+}
+
+func storeUpdateHints(s *state, feed database.Feed, parsed *RSSFeed) updateHints {
+	hints := feedUpdateHints(parsed)
+
+	var value sql.NullString
+	if !hints.isEmpty() {
+		data, err := json.Marshal(hints)
+		if err != nil {
+			log.Printf("encoding of update hints failed: %v", err)
+			return hints
+		}
+		value = sql.NullString{String: string(data), Valid: true}
+	}
+	if value == feed.UpdateHints {
+		return hints
+	}
+
+	err := s.db.SetFeedUpdateHints(context.Background(), database.SetFeedUpdateHintsParams{
+		ID:          feed.ID,
+		UpdateHints: value,
+	})
+	if err != nil {
+		log.Printf("storing of update hints failed: %v", err)
+	}
+	return hints
+}
+
+func scheduleFeed(s *state, feed database.Feed, hints updateHints) {
+	if hints.isEmpty() {
+		return
+	}
+	next := hints.nextFetch(time.Now())
+	err := s.db.ScheduleFeed(context.Background(), database.ScheduleFeedParams{
+		ID:          feed.ID,
+		NextFetchAt: next,
+	})
+	if err != nil {
+		log.Printf("scheduling of feed failed: %v", err)
+		return
+	}
+	log.Printf("Next fetch of %s not before %v, as requested by the feed", feed.Name, next.Format(time.RFC3339))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeedUpdateHints(t *testing.T) {
+	feed := &RSSFeed{}
+	feed.Channel.TTL = "60"
+	feed.Channel.UpdatePeriod = "daily"
+	feed.Channel.UpdateFrequency = "12"
+	feed.Channel.SkipHours = []string{"0", "24", "7", "x"}
+	feed.Channel.SkipDays = []string{"Saturday", "Someday"}
+
+	hints := feedUpdateHints(feed)
+	if want := int64(2 * 60 * 60); hints.IntervalSeconds != want {
+		t.Errorf("interval = %ds, want %ds", hints.IntervalSeconds, want)
+	}
+	if len(hints.SkipHours) != 3 || hints.SkipHours[1] != 0 || hints.SkipHours[2] != 7 {
+		t.Errorf("skip hours = %v, want [0 0 7]", hints.SkipHours)
+	}
+	if len(hints.SkipDays) != 1 || hints.SkipDays[0] != "saturday" {
+		t.Errorf("skip days = %v, want [saturday]", hints.SkipDays)
+	}
+}
+
+func TestUpdateHintsNextFetch(t *testing.T) {
+	// A Friday.
+	now := time.Date(2024, 3, 1, 21, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		hints updateHints
+		want  time.Time
+	}{
+		{
+			name:  "interval",
+			hints: updateHints{IntervalSeconds: 3600},
+			want:  time.Date(2024, 3, 1, 22, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "skipped hours",
+			hints: updateHints{IntervalSeconds: 1800, SkipHours: []int{22, 23}},
+			want:  time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "skipped days",
+			hints: updateHints{SkipHours: []int{21, 22, 23}, SkipDays: []string{"saturday", "sunday"}},
+			want:  time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "everything skipped",
+			hints: updateHints{SkipDays: []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}},
+			want:  time.Date(2024, 3, 9, 21, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hints.nextFetch(now); !got.Equal(tt.want) {
+				t.Errorf("nextFetch = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	feedKindFeed   = "feed"
+	feedKindScrape = "scrape"
+)
+
+type scrapeRules struct {
+	Item    string `json:"item"`
+	Title   string `json:"title,omitempty"`
+	Link    string `json:"link,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+type scrapeFlags struct {
+	rules scrapeRules
+}
+
+func (f *scrapeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.rules.Item, "scrape-item", "", "CSS selector matching each post on a page without a feed")
+	fs.StringVar(&f.rules.Title, "scrape-title", "", "CSS selector of the post title within an item")
+	fs.StringVar(&f.rules.Link, "scrape-link", "", "CSS selector of the post link within an item")
+	fs.StringVar(&f.rules.Date, "scrape-date", "", "CSS selector of the post date within an item")
+	fs.StringVar(&f.rules.Summary, "scrape-summary", "", "CSS selector of the post summary within an item")
+}
+
+func (f *scrapeFlags) isSet() bool {
+	return f.rules != scrapeRules{}
+}
+
+func (r scrapeRules) validate() error {
+	if r.Item == "" {
+		return errors.New("--scrape-item is required to scrape a page")
+	}
+	for _, selector := range []string{r.Item, r.Title, r.Link, r.Date, r.Summary} {
+		if selector == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(selector); err != nil {
+			return fmt.Errorf("invalid selector %q: %v", selector, err)
+		}
+	}
+	return nil
+}
+
+func (r scrapeRules) parse(p *page) (*RSSFeed, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	reader, err := charset.NewReader(bytes.NewReader(p.Data), p.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &RSSFeed{}
+	feed.Channel.Link = p.URL
+	if title := cascadia.Query(doc, cascadia.MustCompile("title")); title != nil {
+		feed.Channel.Title = collapseText(title)
+	}
+
+	items := cascadia.QueryAll(doc, cascadia.MustCompile(r.Item))
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no items matched selector %q", r.Item)
+	}
+	for _, n := range items {
+		item := RSSItem{}
+		if link := r.link(n); link != nil {
+			item.Title = collapseText(link)
+			item.Link = resolveReference(p.URL, attr(link, "href"))
+		}
+		if r.Title != "" {
+			if title := r.match(n, r.Title); title != nil {
+				item.Title = collapseText(title)
+			}
+		}
+		if r.Date != "" {
+			if date := r.match(n, r.Date); date != nil {
+				item.PubDate = attr(date, "datetime")
+				if item.PubDate == "" {
+					item.PubDate = collapseText(date)
+				}
+			}
+		}
+		if r.Summary != "" {
+			item.Description = innerHTML(r.match(n, r.Summary))
+		}
+		if item.Title == "" && item.Link == "" {
+			continue
+		}
+		feed.Channel.Item = append(feed.Channel.Item, item)
+	}
+
+	return feed, nil
+}
+
+func (r scrapeRules) match(item *html.Node, selector string) *html.Node {
+	if selector == "" {
+		return item
+	}
+	return cascadia.Query(item, cascadia.MustCompile(selector))
+}
+
+func (r scrapeRules) link(item *html.Node) *html.Node {
+	n := r.match(item, r.Link)
+	if n == nil || attr(n, "href") != "" {
+		return n
+	}
+	return cascadia.Query(n, cascadia.MustCompile("a[href]"))
+}
+
+func collapseText(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(textContent(n)), " ")
+}
+
+func innerHTML(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&buf, c); err != nil {
+			return ""
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
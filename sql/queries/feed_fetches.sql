@@ -0,0 +1,38 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches(id, created_at, updated_at, feed_id, status_code, duration_ms, item_count, succeeded, error)
+VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8, $9
+       );
+
+-- name: DeleteFeedFetchesBefore :exec
+DELETE FROM feed_fetches
+ WHERE created_at < $1;
+
+-- name: GetFeedHealth :many
+WITH stats AS (
+    SELECT feed_id,
+           (MAX(created_at) FILTER (WHERE succeeded))::timestamp AS last_success_at,
+           AVG(duration_ms)::float8 AS avg_duration_ms,
+           COALESCE(AVG(item_count) FILTER (WHERE status_code = 200), 0)::float8 AS avg_item_count,
+           COUNT(*) AS fetch_count
+      FROM feed_fetches
+     GROUP BY feed_id
+), latest AS (
+    SELECT DISTINCT ON (feed_id)
+           feed_id,
+           status_code
+      FROM feed_fetches
+     ORDER BY feed_id, created_at DESC
+)
+SELECT feeds.*,
+       stats.last_success_at,
+       stats.avg_duration_ms,
+       stats.avg_item_count,
+       stats.fetch_count,
+       latest.status_code AS last_status_code
+  FROM feeds
+  LEFT JOIN stats
+    ON stats.feed_id = feeds.id
+  LEFT JOIN latest
+    ON latest.feed_id = feeds.id
+ ORDER BY feeds.name;
\ No newline at end of file
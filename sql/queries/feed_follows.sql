@@ -36,4 +36,19 @@ SELECT feed_follows.*,
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows
  WHERE user_id = $1
-   AND feed_id = $2;
\ No newline at end of file
+   AND feed_id = $2;
+
+-- name: MoveFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+SELECT gen_random_uuid(),
+       CURRENT_TIMESTAMP,
+       CURRENT_TIMESTAMP,
+       existing.user_id,
+       @to_feed_id::uuid
+  FROM feed_follows AS existing
+ WHERE existing.feed_id = @from_feed_id
+    ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+-- name: DeleteFeedFollowsForFeed :exec
+DELETE FROM feed_follows
+ WHERE feed_id = $1;
\ No newline at end of file
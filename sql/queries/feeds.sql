@@ -1,12 +1,16 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, fetch_full_article, kind, scrape_rules, json_mapping)
 VALUES (
            $1,
            $2,
            $3,
            $4,
             $5,
-            $6
+            $6,
+            $7,
+            $8,
+            $9,
+            $10
        )
 RETURNING *;
 
@@ -16,6 +20,9 @@ SELECT * FROM feeds;
 -- name: GetFeedByURL :one
 SELECT * FROM feeds WHERE url = $1;
 
+-- name: GetFeedsByName :many
+SELECT * FROM feeds WHERE name = $1;
+
 -- name: GetFeedByID :one
 SELECT * FROM feeds WHERE id = $1;
 
@@ -28,5 +35,89 @@ UPDATE feeds
 -- name: GetNextFeedToFetch :one
 SELECT *
   FROM feeds
- ORDER BY last_fetched_at DESC NULLS FIRST
-  LIMIT 1;
\ No newline at end of file
+ WHERE gone_at IS NULL
+   AND disabled_at IS NULL
+   AND (next_fetch_at IS NULL OR next_fetch_at <= @now::timestamp)
+ ORDER BY last_fetched_at ASC NULLS FIRST
+  LIMIT 1;
+
+-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       etag = $2,
+       last_modified = $3
+ WHERE id = $1;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       url = $2
+ WHERE id = $1;
+
+-- name: MarkFeedGone :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       gone_at = CURRENT_TIMESTAMP
+ WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+ WHERE id = $1;
+
+-- name: DeferFeed :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       next_fetch_at = $2
+ WHERE id = $1;
+
+-- name: ScheduleFeed :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       next_fetch_at = GREATEST(COALESCE(next_fetch_at, @next_fetch_at::timestamp), @next_fetch_at::timestamp)
+ WHERE id = @id;
+
+-- name: SetFeedUpdateHints :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       update_hints = $2
+ WHERE id = $1;
+
+-- name: RecordFeedFailure :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       consecutive_failures = $2,
+       last_error = $3,
+       next_fetch_at = $4,
+       disabled_at = $5
+ WHERE id = $1;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       consecutive_failures = 0,
+       last_error = NULL
+ WHERE id = $1;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       consecutive_failures = 0,
+       next_fetch_at = NULL,
+       disabled_at = NULL
+ WHERE id = $1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       title = $2,
+       description = $3,
+       site_url = $4,
+       image_url = $5,
+       language = $6
+ WHERE id = $1;
+
+-- name: SetFeedFetchFullArticle :exec
+UPDATE feeds
+   SET updated_at = CURRENT_TIMESTAMP,
+       fetch_full_article = $2
+ WHERE id = $1;
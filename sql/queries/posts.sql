@@ -1,8 +1,20 @@
 -- name: CreatePost :one
-INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts(id, created_at, updated_at, title, url, description, published_at, published_at_inferred, feed_id, content, author, guid)
 VALUES (
-        $1, $2, $3, $4, $5, $6, $7, $8
+        $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
        )
+ON CONFLICT (feed_id, guid) DO UPDATE
+   SET updated_at = EXCLUDED.updated_at,
+       title = EXCLUDED.title,
+       url = EXCLUDED.url,
+       description = EXCLUDED.description,
+       content = EXCLUDED.content,
+       author = EXCLUDED.author,
+       published_at = CASE
+                          WHEN EXCLUDED.published_at_inferred THEN posts.published_at
+                          ELSE EXCLUDED.published_at
+                      END,
+       published_at_inferred = posts.published_at_inferred AND EXCLUDED.published_at_inferred
 RETURNING *;
 
 -- name: GetPostsForUser :many
@@ -11,4 +23,44 @@ SELECT posts.*
  INNER JOIN feed_follows
     ON posts.feed_id = feed_follows.feed_id
  WHERE feed_follows.user_id = $1
- LIMIT @postLimit;
\ No newline at end of file
+ ORDER BY posts.published_at DESC
+ LIMIT @postLimit;
+
+-- name: SetPostArticle :exec
+UPDATE posts
+   SET updated_at = CURRENT_TIMESTAMP,
+       article = $2
+ WHERE id = $1;
+
+-- name: RecordPostArticleFailure :exec
+UPDATE posts
+   SET updated_at = CURRENT_TIMESTAMP,
+       article_attempts = $2,
+       article_next_fetch_at = $3
+ WHERE id = $1;
+
+-- name: GetPostsWithoutArticle :many
+SELECT posts.*,
+       feeds.url AS feed_url
+  FROM posts
+ INNER JOIN feeds
+    ON feeds.id = posts.feed_id
+ WHERE feeds.fetch_full_article
+   AND posts.article IS NULL
+   AND posts.url <> ''
+   AND posts.article_attempts < @max_attempts
+   AND (posts.article_next_fetch_at IS NULL OR posts.article_next_fetch_at <= @now::timestamp)
+ ORDER BY posts.published_at DESC
+ LIMIT @post_limit;
+
+-- name: MovePosts :exec
+UPDATE posts
+   SET updated_at = CURRENT_TIMESTAMP,
+       feed_id = @to_feed_id
+ WHERE posts.feed_id = @from_feed_id
+   AND NOT EXISTS (
+           SELECT 1
+             FROM posts AS existing
+            WHERE existing.feed_id = @to_feed_id
+              AND existing.guid = posts.guid
+       );
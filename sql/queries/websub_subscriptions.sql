@@ -0,0 +1,45 @@
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions(id, created_at, updated_at, feed_id, hub, topic, secret, next_request_at)
+VALUES (
+        $1, $2, $3, $4, $5, $6, $7, $8
+       )
+ON CONFLICT (feed_id) DO UPDATE
+   SET updated_at = EXCLUDED.updated_at,
+       hub = EXCLUDED.hub,
+       topic = EXCLUDED.topic,
+       secret = EXCLUDED.secret,
+       lease_expires_at = NULL,
+       next_request_at = EXCLUDED.next_request_at
+RETURNING *;
+
+-- name: GetWebSubSubscription :one
+SELECT *
+  FROM websub_subscriptions
+ WHERE id = $1;
+
+-- name: GetWebSubSubscriptionForFeed :one
+SELECT *
+  FROM websub_subscriptions
+ WHERE feed_id = $1;
+
+-- name: GetWebSubSubscriptionsDue :many
+SELECT websub_subscriptions.*
+  FROM websub_subscriptions
+  JOIN feeds ON feeds.id = websub_subscriptions.feed_id
+ WHERE websub_subscriptions.next_request_at <= @now::timestamp
+   AND feeds.gone_at IS NULL
+   AND feeds.disabled_at IS NULL
+ ORDER BY websub_subscriptions.next_request_at;
+
+-- name: SetWebSubNextRequest :exec
+UPDATE websub_subscriptions
+   SET updated_at = CURRENT_TIMESTAMP,
+       next_request_at = $2
+ WHERE id = $1;
+
+-- name: ActivateWebSubSubscription :exec
+UPDATE websub_subscriptions
+   SET updated_at = CURRENT_TIMESTAMP,
+       lease_expires_at = $2,
+       next_request_at = $3
+ WHERE id = $1;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE posts
+    ADD COLUMN content TEXT NOT NULL DEFAULT '',
+    ADD COLUMN author TEXT NOT NULL DEFAULT '',
+    ADD COLUMN guid TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE posts
+    DROP COLUMN content,
+    DROP COLUMN author,
+    DROP COLUMN guid;
\ No newline at end of file
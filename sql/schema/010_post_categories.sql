@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE post_categories(
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    post_id UUID NOT NULL REFERENCES posts
+     ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (post_id, name)
+);
+
+-- +goose Down
+DROP TABLE post_categories;
\ No newline at end of file
@@ -0,0 +1,19 @@
+-- +goose Up
+UPDATE posts
+   SET guid = url
+ WHERE guid = '';
+
+DELETE FROM posts duplicate
+ USING posts original
+ WHERE duplicate.feed_id = original.feed_id
+   AND duplicate.guid = original.guid
+   AND (duplicate.created_at, duplicate.id) > (original.created_at, original.id);
+
+ALTER TABLE posts
+    DROP CONSTRAINT posts_url_key,
+    ADD CONSTRAINT posts_feed_id_guid_key UNIQUE (feed_id, guid);
+
+-- +goose Down
+ALTER TABLE posts
+    DROP CONSTRAINT posts_feed_id_guid_key,
+    ADD CONSTRAINT posts_url_key UNIQUE (url);
\ No newline at end of file
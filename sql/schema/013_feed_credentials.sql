@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE feed_credentials(
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL UNIQUE REFERENCES feeds
+     ON DELETE CASCADE,
+    secret TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE feed_credentials;
\ No newline at end of file
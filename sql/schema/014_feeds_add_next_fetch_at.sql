@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN next_fetch_at TIMESTAMP NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN next_fetch_at;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN last_error TEXT NULL,
+    ADD COLUMN disabled_at TIMESTAMP NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN consecutive_failures,
+    DROP COLUMN last_error,
+    DROP COLUMN disabled_at;
\ No newline at end of file
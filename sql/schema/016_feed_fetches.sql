@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE feed_fetches(
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL REFERENCES feeds
+     ON DELETE CASCADE,
+    status_code INTEGER NULL,
+    duration_ms INTEGER NOT NULL,
+    item_count INTEGER NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    error TEXT NULL
+);
+
+CREATE INDEX feed_fetches_feed_id_created_at_idx ON feed_fetches (feed_id, created_at);
+CREATE INDEX feed_fetches_created_at_idx ON feed_fetches (created_at);
+
+-- +goose Down
+DROP TABLE feed_fetches;
\ No newline at end of file
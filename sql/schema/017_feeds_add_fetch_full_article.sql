@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN fetch_full_article BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE posts
+    ADD COLUMN article TEXT NULL,
+    ADD COLUMN article_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN article_next_fetch_at TIMESTAMP NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN fetch_full_article;
+
+ALTER TABLE posts
+    DROP COLUMN article,
+    DROP COLUMN article_attempts,
+    DROP COLUMN article_next_fetch_at;
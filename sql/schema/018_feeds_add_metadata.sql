@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN title TEXT NOT NULL DEFAULT '',
+    ADD COLUMN description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN site_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN image_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN language TEXT NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN title,
+    DROP COLUMN description,
+    DROP COLUMN site_url,
+    DROP COLUMN image_url,
+    DROP COLUMN language;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE websub_subscriptions(
+    id UUID PRIMARY KEY NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL UNIQUE REFERENCES feeds
+     ON DELETE CASCADE,
+    hub TEXT NOT NULL,
+    topic TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    lease_expires_at TIMESTAMP NULL,
+    next_request_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE websub_subscriptions;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN kind TEXT NOT NULL DEFAULT 'feed',
+    ADD COLUMN scrape_rules TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN kind,
+    DROP COLUMN scrape_rules;
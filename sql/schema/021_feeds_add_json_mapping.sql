@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN json_mapping TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN json_mapping;
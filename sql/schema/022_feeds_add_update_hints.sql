@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN update_hints TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN update_hints;
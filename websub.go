@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/timpinoy/bd-aggregator/internal/database"
+)
+
+const (
+	defaultWebSubListenAddr = ":8080"
+	webSubLeaseSeconds      = 7 * 24 * 60 * 60
+	webSubRetryInterval     = time.Hour
+)
+
+var webSubSignatureHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+func webSubLinks(header http.Header, feed *RSSFeed) (string, string) {
+	links := parseLinkHeader(header.Values("Link"))
+
+	hub := links["hub"]
+	if hub == "" && len(feed.Channel.Hubs) > 0 {
+		hub = feed.Channel.Hubs[0]
+	}
+	topic := links["self"]
+	if topic == "" {
+		topic = feed.Channel.Self
+	}
+	return hub, topic
+}
+
+func parseLinkHeader(values []string) map[string]string {
+	links := make(map[string]string)
+	for _, value := range values {
+		for _, link := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
+
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(param, "=")
+				if !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+					rel = strings.ToLower(rel)
+					if _, ok := links[rel]; !ok {
+						links[rel] = target
+					}
+				}
+			}
+		}
+	}
+	return links
+}
+
+func ensureWebSubSubscription(s *state, feed database.Feed, hub, topic string) {
+	if s.cfg.WebSubCallback == "" {
+		return
+	}
+
+	sub, err := s.db.GetWebSubSubscriptionForFeed(context.Background(), feed.ID)
+	if err == nil && sub.Hub == hub && sub.Topic == topic {
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("retrieval of websub subscription failed: %v", err)
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Printf("generation of websub secret failed: %v", err)
+		return
+	}
+
+	sub, err = s.db.UpsertWebSubSubscription(context.Background(), database.UpsertWebSubSubscriptionParams{
+		ID:            uuid.New(),
+		CreatedAt:     time.Now().UTC(),
+		UpdatedAt:     time.Now().UTC(),
+		FeedID:        feed.ID,
+		Hub:           hub,
+		Topic:         topic,
+		Secret:        hex.EncodeToString(secret),
+		NextRequestAt: time.Now().UTC().Add(webSubRetryInterval),
+	})
+	if err != nil {
+		log.Printf("storing of websub subscription failed: %v", err)
+		return
+	}
+
+	if err := requestWebSubSubscription(s, sub); err != nil {
+		log.Printf("websub subscription of %s at %s failed: %v", feed.Name, hub, err)
+		return
+	}
+	log.Printf("Requested websub subscription of %s at %s", feed.Name, hub)
+}
+
+func renewWebSubSubscriptions(s *state) {
+	subs, err := s.db.GetWebSubSubscriptionsDue(context.Background(), time.Now().UTC())
+	if err != nil {
+		log.Printf("retrieval of websub subscriptions to renew failed: %v", err)
+		return
+	}
+
+	for _, sub := range subs {
+		err = s.db.SetWebSubNextRequest(context.Background(), database.SetWebSubNextRequestParams{
+			ID:            sub.ID,
+			NextRequestAt: time.Now().UTC().Add(webSubRetryInterval),
+		})
+		if err != nil {
+			log.Printf("updating of websub subscription failed: %v", err)
+			continue
+		}
+		if err := requestWebSubSubscription(s, sub); err != nil {
+			log.Printf("renewal of websub subscription to %s failed: %v", sub.Topic, err)
+			continue
+		}
+		log.Printf("Requested renewal of websub subscription to %s", sub.Topic)
+	}
+}
+
+func requestWebSubSubscription(s *state, sub database.WebsubSubscription) error {
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {sub.Topic},
+		"hub.callback":      {webSubCallbackURL(s.cfg.WebSubCallback, sub.ID)},
+		"hub.secret":        {sub.Secret},
+		"hub.lease_seconds": {strconv.Itoa(webSubLeaseSeconds)},
+	}
+	return s.fetcher.PostForm(context.Background(), sub.Hub, form)
+}
+
+func webSubCallbackURL(base string, id uuid.UUID) string {
+	return strings.TrimSuffix(base, "/") + "/" + id.String()
+}
+
+func serveWebSub(s *state) {
+	addr := s.cfg.WebSubListen
+	if addr == "" {
+		addr = defaultWebSubListenAddr
+	}
+
+	log.Printf("Listening for websub callbacks on %s", addr)
+	err := http.ListenAndServe(addr, &webSubHandler{s: s})
+	log.Printf("websub callback server stopped: %v", err)
+}
+
+type webSubHandler struct {
+	s *state
+}
+
+func (h *webSubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var sub *database.WebsubSubscription
+	if id, err := uuid.Parse(path.Base(r.URL.Path)); err == nil {
+		found, err := h.s.db.GetWebSubSubscription(r.Context(), id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("retrieval of websub subscription failed: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err == nil {
+			sub = &found
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.verify(w, r, sub)
+	case http.MethodPost:
+		h.deliver(w, r, sub)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *webSubHandler) verify(w http.ResponseWriter, r *http.Request, sub *database.WebsubSubscription) {
+	query := r.URL.Query()
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		if sub == nil || query.Get("hub.topic") != sub.Topic {
+			http.NotFound(w, r)
+			return
+		}
+
+		leaseSeconds, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || leaseSeconds <= 0 {
+			leaseSeconds = webSubLeaseSeconds
+		}
+		lease := time.Duration(leaseSeconds) * time.Second
+		now := time.Now().UTC()
+		err = h.s.db.ActivateWebSubSubscription(r.Context(), database.ActivateWebSubSubscriptionParams{
+			ID:             sub.ID,
+			LeaseExpiresAt: sql.NullTime{Time: now.Add(lease), Valid: true},
+			NextRequestAt:  now.Add(lease * 9 / 10),
+		})
+		if err != nil {
+			log.Printf("activation of websub subscription failed: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Websub subscription to %s verified for %v", sub.Topic, lease)
+	case "unsubscribe":
+		if sub != nil {
+			http.NotFound(w, r)
+			return
+		}
+	case "denied":
+		if sub != nil {
+			log.Printf("Websub subscription to %s denied by hub: %s", sub.Topic, query.Get("hub.reason"))
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		http.Error(w, "unknown hub.mode", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(query.Get("hub.challenge")))
+}
+
+func (h *webSubHandler) deliver(w http.ResponseWriter, r *http.Request, sub *database.WebsubSubscription) {
+	if sub == nil {
+		http.Error(w, "unknown subscription", http.StatusGone)
+		return
+	}
+	feed, err := h.s.db.GetFeedByID(r.Context(), sub.FeedID)
+	if err != nil {
+		log.Printf("retrieval of feed failed: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	// A 410 tells the hub to drop the subscription; a feed that is enabled
+	// again subscribes anew when its renewal comes due.
+	if feed.GoneAt.Valid || feed.DisabledAt.Valid {
+		http.Error(w, "feed is no longer followed", http.StatusGone)
+		return
+	}
+
+	// The hub signs the body as sent, before any content encoding is undone.
+	raw, err := readEncodedBody(http.Header{}, r.ContentLength, r.Body, h.s.cfg.MaxFeedBytes)
+	var data []byte
+	if err == nil {
+		data, err = readEncodedBody(r.Header, -1, bytes.NewReader(raw), h.s.cfg.MaxFeedBytes)
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLargeErr *feedTooLargeError
+		if errors.As(err, &tooLargeErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	// Deliveries with a bad signature are acknowledged but ignored, as the spec requires.
+	w.WriteHeader(http.StatusAccepted)
+	if !validSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), raw) {
+		log.Printf("Ignoring websub delivery for %s with an invalid signature", sub.Topic)
+		return
+	}
+
+	parsed, err := parseFeedPage(&page{URL: sub.Topic, ContentType: r.Header.Get("Content-Type"), Data: data})
+	if err != nil {
+		log.Printf("parsing of websub delivery for %s failed: %v", feed.Name, err)
+		return
+	}
+
+	postsSaved := savePosts(h.s, feed, parsed)
+	log.Printf("Received %s via websub, %v new posts saved", feed.Name, postsSaved)
+}
+
+func validSignature(secret, header string, body []byte) bool {
+	method, signature, ok := strings.Cut(strings.TrimSpace(header), "=")
+	if !ok {
+		return false
+	}
+	newHash, ok := webSubSignatureHashes[strings.ToLower(method)]
+	if !ok {
+		return false
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
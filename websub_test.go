@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/timpinoy/bd-aggregator/internal/database"
+)
+
+func (q *fakeQuerier) GetWebSubSubscription(ctx context.Context, id uuid.UUID) (database.WebsubSubscription, error) {
+	sub, ok := q.subscriptions[id]
+	if !ok {
+		return database.WebsubSubscription{}, sql.ErrNoRows
+	}
+	return sub, nil
+}
+
+func (q *fakeQuerier) GetWebSubSubscriptionForFeed(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error) {
+	for _, sub := range q.subscriptions {
+		if sub.FeedID == feedID {
+			return sub, nil
+		}
+	}
+	return database.WebsubSubscription{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) UpsertWebSubSubscription(ctx context.Context, arg database.UpsertWebSubSubscriptionParams) (database.WebsubSubscription, error) {
+	sub, err := q.GetWebSubSubscriptionForFeed(ctx, arg.FeedID)
+	if err != nil {
+		sub = database.WebsubSubscription{ID: arg.ID, CreatedAt: arg.CreatedAt, FeedID: arg.FeedID}
+	}
+	sub.UpdatedAt = arg.UpdatedAt
+	sub.Hub = arg.Hub
+	sub.Topic = arg.Topic
+	sub.Secret = arg.Secret
+	sub.LeaseExpiresAt = sql.NullTime{}
+	sub.NextRequestAt = arg.NextRequestAt
+	q.subscriptions[sub.ID] = sub
+	return sub, nil
+}
+
+func (q *fakeQuerier) ActivateWebSubSubscription(ctx context.Context, arg database.ActivateWebSubSubscriptionParams) error {
+	sub := q.subscriptions[arg.ID]
+	sub.LeaseExpiresAt = arg.LeaseExpiresAt
+	sub.NextRequestAt = arg.NextRequestAt
+	q.subscriptions[arg.ID] = sub
+	return nil
+}
+
+func newTestSubscription(q *fakeQuerier) database.WebsubSubscription {
+	sub := database.WebsubSubscription{
+		ID:     uuid.New(),
+		FeedID: q.feed.ID,
+		Hub:    "https://hub.example.com/",
+		Topic:  "https://example.com/feed.xml",
+		Secret: "s3cret",
+	}
+	q.subscriptions[sub.ID] = sub
+	return sub
+}
+
+func TestScrapeNextFeedSubscribesToHub(t *testing.T) {
+	requests := make(chan url.Values, 1)
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parsing of subscription request failed: %v", err)
+		}
+		requests <- r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	feed := readFixture(t, "rss.xml")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Add("Link", `<`+hub.URL+`>; rel="hub", <https://example.com/feed.xml>; rel="self"`)
+		w.Write(feed)
+	}))
+	defer server.Close()
+
+	s, q := newTestState(t, server.URL+"/feed.xml")
+	s.cfg.WebSubCallback = "https://gator.example.com/websub/"
+	scrapeNextFeed(s)
+
+	sub, err := q.GetWebSubSubscriptionForFeed(context.Background(), q.feed.ID)
+	if err != nil {
+		t.Fatalf("no subscription stored: %v", err)
+	}
+	if sub.Hub != hub.URL || sub.Topic != "https://example.com/feed.xml" {
+		t.Errorf("subscription = %s %s, want the advertised hub and self link", sub.Hub, sub.Topic)
+	}
+
+	form := <-requests
+	if form.Get("hub.mode") != "subscribe" || form.Get("hub.topic") != sub.Topic || form.Get("hub.secret") != sub.Secret {
+		t.Errorf("subscription request = %v", form)
+	}
+	if want := "https://gator.example.com/websub/" + sub.ID.String(); form.Get("hub.callback") != want {
+		t.Errorf("callback = %q, want %q", form.Get("hub.callback"), want)
+	}
+}
+
+func TestWebSubHandlerVerifiesSubscription(t *testing.T) {
+	s, q := newTestState(t, "https://example.com/feed.xml")
+	sub := newTestSubscription(q)
+	handler := &webSubHandler{s: s}
+
+	query := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {sub.Topic},
+		"hub.challenge":     {"abc123"},
+		"hub.lease_seconds": {"3600"},
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/websub/"+sub.ID.String()+"?"+query.Encode(), nil))
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "abc123" {
+		t.Fatalf("response = %d %q, want 200 echoing the challenge", rec.Code, rec.Body.String())
+	}
+	lease := q.subscriptions[sub.ID].LeaseExpiresAt
+	if !lease.Valid || lease.Time.Before(time.Now().UTC().Add(59*time.Minute)) {
+		t.Errorf("lease expires at %v, want about an hour from now", lease.Time)
+	}
+
+	query.Set("hub.topic", "https://example.com/other.xml")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/websub/"+sub.ID.String()+"?"+query.Encode(), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("verification of another topic returned %d, want 404", rec.Code)
+	}
+}
+
+func TestWebSubHandlerIngestsSignedDelivery(t *testing.T) {
+	s, q := newTestState(t, "https://example.com/feed.xml")
+	sub := newTestSubscription(q)
+	handler := &webSubHandler{s: s}
+	body := readFixture(t, "rss.xml")
+
+	deliver := func(signature string) int {
+		req := httptest.NewRequest("POST", "/websub/"+sub.ID.String(), strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/rss+xml")
+		if signature != "" {
+			req.Header.Set("X-Hub-Signature", signature)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := deliver("sha256=00"); code != http.StatusAccepted || len(q.posts) != 0 {
+		t.Fatalf("badly signed delivery: status %d, %d posts saved, want 202 and none", code, len(q.posts))
+	}
+
+	mac := hmac.New(sha256.New, []byte(sub.Secret))
+	mac.Write(body)
+	if code := deliver("sha256=" + hex.EncodeToString(mac.Sum(nil))); code != http.StatusAccepted {
+		t.Fatalf("signed delivery: status %d, want 202", code)
+	}
+	if len(q.posts) != 2 {
+		t.Fatalf("saved %d posts, want 2", len(q.posts))
+	}
+	if post := q.post(t, "https://example.com/posts/first"); post.Title != "First & foremost" {
+		t.Errorf("title = %q, want %q", post.Title, "First & foremost")
+	}
+}
+
+func TestWebSubHandlerVerifiesSignatureOfEncodedBody(t *testing.T) {
+	s, q := newTestState(t, "https://example.com/feed.xml")
+	sub := newTestSubscription(q)
+	handler := &webSubHandler{s: s}
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	zw.Write(readFixture(t, "rss.xml"))
+	zw.Close()
+	mac := hmac.New(sha256.New, []byte(sub.Secret))
+	mac.Write(body.Bytes())
+
+	req := httptest.NewRequest("POST", "/websub/"+sub.ID.String(), bytes.NewReader(body.Bytes()))
+	req.Header.Set("Content-Type", "application/rss+xml")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status %d, want 202", rec.Code)
+	}
+	if len(q.posts) != 2 {
+		t.Errorf("saved %d posts, want 2 from a delivery signed over its gzipped body", len(q.posts))
+	}
+}
+
+func TestWebSubHandlerRejectsDeliveryForInactiveFeed(t *testing.T) {
+	body := readFixture(t, "rss.xml")
+
+	for _, tt := range []struct {
+		name  string
+		apply func(feed *database.Feed)
+	}{
+		{"gone", func(feed *database.Feed) { feed.GoneAt = sql.NullTime{Time: time.Now().UTC(), Valid: true} }},
+		{"disabled", func(feed *database.Feed) { feed.DisabledAt = sql.NullTime{Time: time.Now().UTC(), Valid: true} }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, q := newTestState(t, "https://example.com/feed.xml")
+			tt.apply(&q.feed)
+			sub := newTestSubscription(q)
+			handler := &webSubHandler{s: s}
+
+			mac := hmac.New(sha256.New, []byte(sub.Secret))
+			mac.Write(body)
+			req := httptest.NewRequest("POST", "/websub/"+sub.ID.String(), bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/rss+xml")
+			req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusGone {
+				t.Errorf("status %d, want 410 so the hub drops the subscription", rec.Code)
+			}
+			if len(q.posts) != 0 {
+				t.Errorf("saved %d posts, want none", len(q.posts))
+			}
+		})
+	}
+}
+
+func TestParseLinkHeader(t *testing.T) {
+	links := parseLinkHeader([]string{
+		`<https://hub.example.com/>; rel="hub", <https://example.com/feed>; rel="self alternate"`,
+		`<https://other-hub.example.com/>; rel=hub`,
+	})
+
+	if links["hub"] != "https://hub.example.com/" {
+		t.Errorf("hub = %q, want the first advertised hub", links["hub"])
+	}
+	if links["self"] != "https://example.com/feed" || links["alternate"] != "https://example.com/feed" {
+		t.Errorf("self = %q, alternate = %q", links["self"], links["alternate"])
+	}
+}